@@ -0,0 +1,287 @@
+package ranktree
+
+import (
+	"math/bits"
+	"sort"
+)
+
+
+// backend stores the leaf nodes of a RankTree and counts their members.
+// Leaf nodes are addressed by position, i.e. score - RankTree.low, in [0, size).
+type backend interface {
+	// Clears all the leaf nodes and counts, and resizes the backend to <size> positions.
+	reset(size int)
+
+	// Doubles the size, the current leaf nodes and counts are moved to the upper half if <upper> is true,
+	// otherwise they are kept in the lower half.
+	grow(upper bool)
+
+	// Returns the leaf node at <pos>, nil if there is none.
+	leaf(pos int) *TreeNode
+
+	// Stores <node> as the leaf node at <pos>.
+	setLeaf(pos int, node *TreeNode)
+
+	// Returns a new backend of the same type, reset() must be called before use.
+	empty() backend
+
+	// Returns a copy of the backend sharing the storage, e.g. for a snapshot, which must not be written.
+	// The storage is copied on write by the backend from now on, see cowArray.
+	share() backend
+
+	// Increases the count of <pos> by <delta>.
+	add(pos, delta int)
+
+	// Increases the counts of sorted <positions> by <deltas>.
+	addAll(positions, deltas []int)
+
+	// Returns the number of members at positions less than <pos>, 0 <= pos <= size.
+	prefix(pos int) int
+
+	// Returns the number of members at positions less than each of sorted <positions>.
+	prefixAll(positions []int) []int
+
+	// Returns the position of the member with <rank>, counted from the lowest position,
+	// and its offset among the members at the position.
+	search(rank int) (pos, offset int)
+
+	// Returns the maximum of high - low of the score range, see ErrRangeTooLarge.
+	maxRange() int
+}
+
+
+// Returns whether the score range [low, high] is too large for <b>, low <= high.
+// The difference is unsigned, so that it does not overflow.
+func rangeTooLarge(b backend, low, high int) bool {
+	return uint64(high) - uint64(low) > uint64(b.maxRange())
+}
+
+
+// defaultBackend creates the backend of a RankTree when no backend option is given.
+var defaultBackend = newSegmentTree
+
+
+// denseLeaves stores the leaf nodes in a slice indexed by position, it is shared by the backends
+// which take memory for every position of the range anyway.
+type denseLeaves struct {
+	leaves	cowArray[*TreeNode]		// nil until used
+	gen		uint64					// generation of the storage of the backend, see share()
+}
+
+
+// Number of scores the dense backends accept, they take memory for every position.
+const maxDenseRange = 1 << 24
+
+
+func (d *denseLeaves) maxRange() int {
+	return maxDenseRange - 1
+}
+
+
+func (d *denseLeaves) leaf(pos int) *TreeNode {
+	return d.leaves.get(pos)
+}
+
+
+func (d *denseLeaves) setLeaf(pos int, node *TreeNode) {
+	d.leaves.set(pos, node, d.gen)
+}
+
+
+// Clears the leaf nodes, and resizes to <size> positions in a new generation.
+func (d *denseLeaves) resizeLeaves(size int) {
+	d.gen = newGen()
+	d.leaves = newCowArray[*TreeNode](size)
+}
+
+
+// Doubles the number of positions, see backend.grow().
+func (d *denseLeaves) growLeaves(upper bool) {
+	leaves := d.leaves
+	d.resizeLeaves(leaves.size * 2)
+
+	offset := 0
+	if upper {
+		offset = leaves.size
+	}
+	for pos := 0; pos < leaves.size; pos++ {
+		if node := leaves.get(pos); node != nil {
+			d.setLeaf(offset + pos, node)
+		}
+	}
+}
+
+
+// segmentTree is the default backend, a complete binary tree stored implicitly in a flat array in heap order.
+// The children of node i are 2i+1 and 2i+2, and the root covers the positions [0, size).
+// A node with the range [l, h] (l < h) is split at mid = l + (h - l) / 2.
+type segmentTree struct {
+	denseLeaves
+	counts	cowArray[int32]		// number of members of each node, in heap order
+	size	int					// number of leaf nodes
+}
+
+
+func newSegmentTree() backend {
+	return new(segmentTree)
+}
+
+
+func (seg *segmentTree) empty() backend {
+	return newSegmentTree()
+}
+
+
+func (seg *segmentTree) share() backend {
+	c := *seg
+	seg.gen = newGen()
+	return &c
+}
+
+
+// Returns the length of the heap array of a tree with <size> leaf nodes.
+func segmentTreeLen(size int) int {
+	return 1 << (bits.Len(uint(size - 1)) + 1) - 1
+}
+
+
+func (seg *segmentTree) reset(size int) {
+	seg.resizeLeaves(size)
+	seg.counts = newCowArray[int32](segmentTreeLen(size))
+	seg.size = size
+}
+
+
+// The old root becomes a child of the new root, which has the same range on both sides,
+// so level d of the old tree is copied to level d + 1 of the new one.
+func (seg *segmentTree) grow(upper bool) {
+	counts := seg.counts
+	seg.growLeaves(upper)
+	seg.counts = newCowArray[int32](segmentTreeLen(seg.size * 2))
+	seg.size *= 2
+
+	// the missing pages of the new counts are zero, only non-zero counts are copied
+	for width := 1; width <= counts.size; width *= 2 {
+		offset := width
+		if upper {
+			offset += width
+		}
+		for i := width - 1; i < 2 * width - 1; i++ {
+			if n := counts.get(i); n != 0 {
+				seg.counts.set(i + offset, n, seg.gen)
+			}
+		}
+	}
+	seg.counts.set(0, counts.get(0), seg.gen)
+}
+
+
+func (seg *segmentTree) add(pos, delta int) {
+	i, low, high := 0, 0, seg.size - 1
+	for {
+		*seg.counts.edit(i, seg.gen) += int32(delta)
+		if low == high {
+			break
+		}
+
+		mid := low + (high - low) / 2
+		if pos <= mid {
+			i, high = 2 * i + 1, mid
+		} else {
+			i, low = 2 * i + 2, mid + 1
+		}
+	}
+}
+
+
+// Each node is updated only once.
+func (seg *segmentTree) addAll(positions, deltas []int) {
+	seg.addRange(0, 0, seg.size - 1, positions, deltas)
+}
+
+
+// Basic Function of addAll(), for the subtree of node i with the range [low, high].
+// Returns the total delta of the subtree.
+func (seg *segmentTree) addRange(i, low, high int, positions, deltas []int) (sum int) {
+	if low == high {
+		for _, delta := range deltas {
+			sum += delta
+		}
+	} else {
+		mid := low + (high - low) / 2
+		k := sort.SearchInts(positions, mid + 1)
+		if k > 0 {
+			sum += seg.addRange(2 * i + 1, low, mid, positions[:k], deltas[:k])
+		}
+		if k < len(positions) {
+			sum += seg.addRange(2 * i + 2, mid + 1, high, positions[k:], deltas[k:])
+		}
+	}
+	if sum != 0 {
+		*seg.counts.edit(i, seg.gen) += int32(sum)
+	}
+	return
+}
+
+
+func (seg *segmentTree) prefix(pos int) (sum int) {
+	if pos >= seg.size {
+		return int(seg.counts.get(0))
+	}
+
+	i, low, high := 0, 0, seg.size - 1
+	for low < high {
+		mid := low + (high - low) / 2
+		if pos <= mid {
+			i, high = 2 * i + 1, mid
+		} else {
+			sum += int(seg.counts.get(2 * i + 1))
+			i, low = 2 * i + 2, mid + 1
+		}
+	}
+	return
+}
+
+
+// Nodes shared by positions are walked only once.
+func (seg *segmentTree) prefixAll(positions []int) []int {
+	sums := make([]int, len(positions))
+	seg.prefixRange(0, 0, seg.size - 1, 0, positions, sums)
+	return sums
+}
+
+
+// Basic Function of prefixAll(), for the subtree of node i with the range [low, high].
+// <base> is the number of members at positions less than <low>.
+func (seg *segmentTree) prefixRange(i, low, high, base int, positions, sums []int) {
+	if low == high {
+		for k := range sums {
+			sums[k] = base
+		}
+		return
+	}
+
+	mid := low + (high - low) / 2
+	k := sort.SearchInts(positions, mid + 1)
+	if k > 0 {
+		seg.prefixRange(2 * i + 1, low, mid, base, positions[:k], sums[:k])
+	}
+	if k < len(positions) {
+		seg.prefixRange(2 * i + 2, mid + 1, high, base + int(seg.counts.get(2 * i + 1)), positions[k:], sums[k:])
+	}
+}
+
+
+func (seg *segmentTree) search(rank int) (pos, offset int) {
+	i, low, high := 0, 0, seg.size - 1
+	for low < high {
+		mid := low + (high - low) / 2
+		if left := int(seg.counts.get(2 * i + 1)); rank < left {
+			i, high = 2 * i + 1, mid
+		} else {
+			rank -= left
+			i, low = 2 * i + 2, mid + 1
+		}
+	}
+	return low, rank
+}
@@ -0,0 +1,248 @@
+package ranktree
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"testing"
+)
+
+
+// Backend of the RankTrees created by the tests without a backend option,
+// e.g. go test -backend fenwick runs the test suite with the Fenwick tree.
+var testBackend = flag.String("backend", "segment", "default backend of the tests: segment, fenwick or skiplist")
+
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	switch *testBackend {
+	case "segment":
+	case "fenwick":
+		defaultBackend = newFenwickTree
+	case "skiplist":
+		defaultBackend = newSkipList
+	default:
+		fmt.Fprintf(os.Stderr, "unknown backend %q\n", *testBackend)
+		os.Exit(2)
+	}
+	os.Exit(m.Run())
+}
+
+
+// Checks <b> against the counts of the positions.
+func checkBackend(t *testing.T, name string, b backend, counts []int) {
+	sum := 0
+	for pos, n := range counts {
+		if p := b.prefix(pos); p != sum {
+			t.Errorf("%s.prefix(%d) = %d, want %d", name, pos, p, sum)
+		}
+		for k := 0; k < n; k++ {
+			if p, o := b.search(sum + k); p != pos || o != k {
+				t.Errorf("%s.search(%d) = (%d, %d), want (%d, %d)", name, sum + k, p, o, pos, k)
+			}
+		}
+		sum += n
+	}
+
+	if p := b.prefix(len(counts)); p != sum {
+		t.Errorf("%s.prefix(%d) = %d, want %d", name, len(counts), p, sum)
+	}
+}
+
+
+func TestBackend(t *testing.T) {
+	backends := map[string]func() backend{
+		"segment": newSegmentTree,
+		"fenwick": newFenwickTree,
+		"skiplist": newSkipList,
+	}
+
+	for name, newBackend := range backends {
+		r := rand.New(rand.NewSource(1))
+		b := newBackend()
+		counts := make([]int, 13)
+		b.reset(len(counts))
+
+		for i := 0; i < 100; i++ {
+			pos := r.Intn(len(counts))
+			b.add(pos, 1)
+			counts[pos]++
+		}
+		checkBackend(t, name, b, counts)
+
+		positions, deltas := []int{0, 5, 6, 12}, []int{3, -1, 2, -counts[12]}
+		b.addAll(positions, deltas)
+		for i, pos := range positions {
+			counts[pos] += deltas[i]
+		}
+		checkBackend(t, name, b, counts)
+
+		sums := b.prefixAll([]int{0, 1, 7, 13})
+		if want := []int{0, counts[0], b.prefix(7), b.prefix(13)}; fmt.Sprint(sums) != fmt.Sprint(want) {
+			t.Errorf("%s.prefixAll() = %v, want %v", name, sums, want)
+		}
+
+		// writes after share() are not seen by the shared copy
+		shared, sharedCounts := b.share(), slices.Clone(counts)
+		for i := 0; i < 50; i++ {
+			pos := r.Intn(len(counts))
+			b.add(pos, 1)
+			counts[pos]++
+		}
+		b.setLeaf(3, &TreeNode{score: 3})
+		checkBackend(t, name, b, counts)
+		checkBackend(t, name, shared, sharedCounts)
+		if shared.leaf(3) != nil {
+			t.Errorf("%s.share().leaf(3) = %v after setLeaf(3)", name, shared.leaf(3))
+		}
+
+		// 13 -> 26 (upper) -> 52 (lower)
+		b.grow(true)
+		counts = append(make([]int, len(counts)), counts...)
+		checkBackend(t, name, b, counts)
+
+		b.grow(false)
+		counts = append(counts, make([]int, len(counts))...)
+		checkBackend(t, name, b, counts)
+		checkBackend(t, name, shared, sharedCounts)
+	}
+}
+
+
+func TestWithFenwick(t *testing.T) {
+	tree, err := New(10, 20, WithFenwick(), WithAutoExtend())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := tree.backend.(*fenwickTree); ok == false {
+		t.Fatalf("tree.backend = %T, want *fenwickTree", tree.backend)
+	}
+
+	tree.Add("a", 15)
+	tree.Add("b", 15)
+	tree.Add("c", 20)
+	tree.Add("d", 100)
+	tree.Add("e", 3)
+
+	checkTreeCounts(t, tree)
+	checkRank(t, tree.Range(0, -1), []string{"e", "a", "b", "c", "d"})
+	if n := tree.Rank("c"); n != 3 {
+		t.Errorf("tree.Rank(\"c\") = %d, want %d", n, 3)
+	}
+	if n := tree.Count(15, 99); n != 3 {
+		t.Errorf("tree.Count(15, 99) = %d, want %d", n, 3)
+	}
+}
+
+
+func TestWithSkipList(t *testing.T) {
+	tree, err := New(0, math.MaxInt, WithSkipList())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := tree.backend.(*skipList); ok == false {
+		t.Fatalf("tree.backend = %T, want *skipList", tree.backend)
+	}
+
+	// sparse scores across the whole range
+	tree.Add("a", 1700000000000)
+	tree.Add("b", 0)
+	tree.Add("c", math.MaxInt)
+	tree.Add("d", 1700000000000)
+	tree.Add("e", 1 << 40)
+
+	checkRank(t, tree.Range(0, -1), []string{"b", "e", "a", "d", "c"})
+	checkRank(t, tree.RevRange(0, 1), []string{"c", "a"})
+	if n := tree.Rank("d"); n != 3 {
+		t.Errorf("tree.Rank(\"d\") = %d, want %d", n, 3)
+	}
+	if n := tree.RevRank("e"); n != 3 {
+		t.Errorf("tree.RevRank(\"e\") = %d, want %d", n, 3)
+	}
+	if n := tree.Count(1, math.MaxInt); n != 4 {
+		t.Errorf("tree.Count(1, math.MaxInt) = %d, want %d", n, 4)
+	}
+	checkRankWithScore(t, tree.RevRangeByScore(1 << 40, math.MaxInt), []string{"c", "a", "d", "e"},
+		[]int{math.MaxInt, 1700000000000, 1700000000000, 1 << 40})
+
+	if rank := tree.PopMax(); rank == nil || rank.Member != "c" {
+		t.Errorf("tree.PopMax() = %v, want c", rank)
+	}
+	if rank := tree.PopMin(); rank == nil || rank.Member != "b" {
+		t.Errorf("tree.PopMin() = %v, want b", rank)
+	}
+
+	// empty leaf nodes are released
+	tree.Remove("e")
+	if node := tree.find(1 << 40); node != nil {
+		t.Errorf("tree.find(1 << 40) = %v, want nil", node)
+	}
+	checkRank(t, tree.Range(0, -1), []string{"a", "d"})
+}
+
+
+func TestNewUnbounded(t *testing.T) {
+	tree, err := NewUnbounded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sl, ok := tree.backend.(*skipList); ok == false || sl.size <= 0 || tree.low != 0 || tree.high != math.MaxInt {
+		t.Fatalf("tree.backend = %T, range = (%d, %d)", tree.backend, tree.low, tree.high)
+	}
+
+	tree.Add("a", 0)
+	tree.Add("b", 1 << 62)
+	tree.Add("c", math.MaxInt)
+	tree.Add("d", math.MaxInt - 1)
+
+	if n := tree.Count(0, math.MaxInt); n != 4 {
+		t.Errorf("tree.Count(0, math.MaxInt) = %d, want %d", n, 4)
+	}
+	if n := tree.Count(math.MaxInt, math.MaxInt); n != 1 {
+		t.Errorf("tree.Count(math.MaxInt, math.MaxInt) = %d, want %d", n, 1)
+	}
+	want := []Bucket{{0, 1 << 62 - 1, 1}, {1 << 62, math.MaxInt, 3}}
+	if buckets := tree.Histogram(1 << 62); len(buckets) != 2 || buckets[0] != want[0] || buckets[1] != want[1] {
+		t.Errorf("tree.Histogram(1 << 62) = %v, want %v", buckets, want)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("tree.Validate() = %v", err)
+	}
+
+	// the range is already the largest
+	if err := tree.ExtendRange(0, math.MaxInt); err != nil || tree.low != 0 || tree.high != math.MaxInt {
+		t.Errorf("tree.ExtendRange(0, math.MaxInt) = %v, range = (%d, %d)", err, tree.low, tree.high)
+	}
+	checkRankWithScore(t, tree.RevRangeByScore(1, math.MaxInt), []string{"c", "d", "b"}, []int{math.MaxInt, math.MaxInt - 1, 1 << 62})
+
+	// extended up to math.MaxInt
+	tree, _ = New(10, 100, WithSkipList(), WithAutoExtend())
+	if tree.Add("a", math.MaxInt) == false || tree.Add("b", 0) == false || tree.high != math.MaxInt || tree.low != 0 {
+		t.Errorf("tree.Add() extending to math.MaxInt failed, range = (%d, %d)", tree.low, tree.high)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Errorf("tree.Validate() = %v", err)
+	}
+
+	// an increment beyond math.MaxInt leaves the member unchanged
+	if n := tree.IncrementBy("a", 1); n != -1 || tree.Score("a") != math.MaxInt || tree.Rank("a") != 1 {
+		t.Errorf("tree.IncrementBy(\"a\", 1) = %d, score = %d", n, tree.Score("a"))
+	}
+	if _, err := tree.Apply([]Op{{Type: OpIncrement, Member: "a", Score: math.MaxInt}}); errors.Is(err, ErrOutOfRange) == false {
+		t.Errorf("tree.Apply() = %v, want %v", err, ErrOutOfRange)
+	}
+	if n := tree.IncrementBy("a", -1); n != math.MaxInt - 1 {
+		t.Errorf("tree.IncrementBy(\"a\", -1) = %d, want %d", n, math.MaxInt - 1)
+	}
+
+	if _, err := NewUnbounded(WithFenwick()); errors.Is(err, ErrRangeTooLarge) == false {
+		t.Errorf("NewUnbounded(WithFenwick()) = %v, want %v", err, ErrRangeTooLarge)
+	}
+}
@@ -0,0 +1,205 @@
+package ranktree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+
+// OpType represents the kind of an Op.
+type OpType int
+
+const (
+	OpAdd		OpType = iota	// add a member, see Add()
+	OpIncrement					// increment the score of a member, see IncrementBy()
+	OpUpdate					// update the score of a member, see UpdateScore()
+	OpRemove					// remove a member, see Remove()
+)
+
+
+// Errors returned by Apply().
+var (
+	ErrOutOfRange		= errors.New("score out of the range")
+	ErrMemberExists		= errors.New("member exists")
+	ErrMemberNotFound	= errors.New("member does not exist")
+)
+
+
+// Op is a single mutation of a batch, see Apply().
+type Op struct {
+	Type	OpType
+	Member	string
+	Score	int		// score of OpAdd and OpUpdate, or increment of OpIncrement
+	Insert	bool	// whether OpUpdate adds a member which does not exist
+}
+
+
+// OpResult is the result of an Op.
+type OpResult struct {
+	Score	int		// score of the member after the Op, -1 if it does not exist
+	Changed	bool	// whether the Op added, removed or changed the score of the member
+}
+
+
+// Apply applies <batch> to the RankTree atomically.
+// All the ops are validated before any of them is applied, if an op would fail
+// (the score out of the range, OpAdd of an existing member, or OpUpdate of a member
+// which does not exist without Insert), an error is returned and the RankTree is not changed.
+// If the RankTree is created with WithAutoExtend(), the range is extended once for the whole batch.
+// Ops are applied in order, later ops see the results of earlier ones.
+// Callers which share the RankTree take their lock once around Apply(), see RankTree.
+// Returns the result of each op in order.
+func (tree *RankTree) Apply(batch []Op) ([]OpResult, error) {
+	results := make([]OpResult, len(batch))
+
+	// validate against a shadow of the touched members
+	shadow := make(map[string]int)
+	var touched []string
+	low, high := tree.minScore, tree.maxScore
+	score := func(member string) int {
+		if v, ok := shadow[member]; ok {
+			return v
+		}
+		return tree.Score(member)
+	}
+
+	for i, op := range batch {
+		current := score(op.Member)
+		next := current
+
+		switch op.Type {
+		case OpAdd:
+			if current >= 0 {
+				return nil, fmt.Errorf("op %d: %w", i, ErrMemberExists)
+			}
+			next = op.Score
+		case OpIncrement:
+			next = op.Score
+			if current >= 0 {
+				next += current
+			}
+		case OpUpdate:
+			if current < 0 && op.Insert == false {
+				return nil, fmt.Errorf("op %d: %w", i, ErrMemberNotFound)
+			}
+			next = op.Score
+		case OpRemove:
+			next = -1
+		default:
+			return nil, fmt.Errorf("op %d: unknown op type %d", i, op.Type)
+		}
+
+		if op.Type != OpRemove {
+			if next < 0 || (tree.autoExtend == false && (next < tree.minScore || next > tree.maxScore)) {
+				return nil, fmt.Errorf("op %d: %w", i, ErrOutOfRange)
+			}
+			low, high = min(low, next), max(high, next)
+		}
+
+		if _, ok := shadow[op.Member]; ok == false {
+			touched = append(touched, op.Member)
+		}
+		shadow[op.Member] = next
+		results[i] = OpResult{next, next != current}
+	}
+
+	if low < tree.minScore || high > tree.maxScore {
+		if err := tree.ExtendRange(low, high); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrOutOfRange, err)
+		}
+	}
+
+	// observers need every change, apply ops one by one
+	if len(tree.observers) > 0 || len(tree.watchers) > 0 {
+		for _, op := range batch {
+			switch op.Type {
+			case OpAdd:
+				tree.Add(op.Member, op.Score)
+			case OpIncrement:
+				tree.IncrementBy(op.Member, op.Score)
+			case OpUpdate:
+				tree.UpdateScore(op.Member, op.Score, op.Insert)
+			case OpRemove:
+				tree.Remove(op.Member)
+			}
+		}
+		return results, nil
+	}
+
+	tree.applyFinalScores(touched, shadow)
+	return results, nil
+}
+
+
+// Moves <members> to their final scores (-1 to remove).
+// Counts of the ancestors are updated once per node for the whole batch.
+func (tree *RankTree) applyFinalScores(members []string, scores map[string]int) {
+	deltas := make(map[int]int)
+	touched := make(map[*TreeNode]bool)
+
+	for _, member := range members {
+		old, ok := tree.scores.get(member)
+		score := scores[member]
+		if ok && old == score {
+			continue
+		}
+
+		// remove from the old leaf
+		if ok {
+			node := tree.own(tree.backend.leaf(old - tree.low))
+			i := sort.SearchStrings(node.members, member)
+			node.members = append(node.members[:i], node.members[i+1:]...)
+			tree.scores.delete(member, tree.gen)
+			tree.count--
+			deltas[old]--
+			touched[node] = true
+		}
+
+		// add to the new leaf
+		if score >= 0 {
+			node := tree.leaf(score)
+			i := sort.SearchStrings(node.members, member)
+			node.members = append(node.members, "")
+			copy(node.members[i+1:], node.members[i:])
+			node.members[i] = member
+			tree.scores.set(member, score, tree.gen)
+			tree.count++
+			deltas[score]++
+			touched[node] = true
+		}
+	}
+
+	var emptied, filled []*TreeNode
+	for node := range touched {
+		if len(node.members) == 0 && node.element != 0 {
+			emptied = append(emptied, node)
+		} else if len(node.members) > 0 && node.element == 0 {
+			filled = append(filled, node)
+		}
+	}
+
+	// remove emptied leaves from the list
+	for _, node := range emptied {
+		tree.list.remove(node, tree.gen)
+	}
+
+	// update counts, the backend walks the paths shared by leaves once
+	changed := make([]int, 0, len(deltas))
+	for score, delta := range deltas {
+		if delta != 0 {
+			changed = append(changed, score)
+		}
+	}
+	if len(changed) > 0 {
+		sort.Ints(changed)
+		tree.incrementCounts(changed, deltas)
+	}
+
+	// insert filled leaves into the list from the highest score,
+	// so that the next greater node is already in the list
+	sort.Slice(filled, func(i, j int) bool { return filled[i].score > filled[j].score })
+	for _, node := range filled {
+		tree.list.insertAfter(node, tree.findNextGreaterNode(node.score), tree.gen)
+	}
+}
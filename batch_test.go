@@ -0,0 +1,151 @@
+package ranktree
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+
+// Checks that the linked list contains exactly the non-empty leaves from the highest score.
+func checkListOrder(t *testing.T, tree *RankTree) {
+	var want []*TreeNode
+	for s := tree.maxScore; s >= tree.minScore; s-- {
+		if node := tree.find(s); node != nil && len(node.members) > 0 {
+			want = append(want, node)
+		}
+	}
+
+	if n := tree.list.len; n != len(want) {
+		t.Errorf("tree.list.len = %d, want %d", n, len(want))
+	}
+
+	i := 0
+	for node := tree.list.head(); node != nil; node = tree.list.next(node) {
+		if i >= len(want) || node != want[i] {
+			t.Errorf("tree.list[%d] = %d, want %v", i, node.score, want)
+			return
+		}
+		i++
+	}
+}
+
+
+func TestRankTree_Apply(t *testing.T) {
+	tree, err := New(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 1)
+	tree.Add("b", 2)
+
+	results, err := tree.Apply([]Op{
+		{Type: OpAdd, Member: "c", Score: 3},
+		{Type: OpIncrement, Member: "a", Score: 4},
+		{Type: OpIncrement, Member: "a", Score: 1},
+		{Type: OpUpdate, Member: "d", Score: 8, Insert: true},
+		{Type: OpRemove, Member: "b"},
+		{Type: OpRemove, Member: "x"},
+		{Type: OpUpdate, Member: "c", Score: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []OpResult{{3, true}, {5, true}, {6, true}, {8, true}, {-1, true}, {-1, false}, {3, false}}
+	for i, r := range results {
+		if r != want[i] {
+			t.Errorf("results[%d] = %v, want %v", i, r, want[i])
+		}
+	}
+
+	checkRankWithScore(t, tree.RangeWithScore(0, -1), []string{"c", "a", "d"}, []int{3, 6, 8})
+	checkRankTree(t, tree, 1, 8, 3)
+	checkListOrder(t, tree)
+}
+
+
+func TestRankTree_ApplyAtomic(t *testing.T) {
+	tree, err := New(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 1)
+	tree.Add("b", 2)
+
+	tests := []struct {
+		op	Op
+		err	error
+	}{
+		{Op{Type: OpAdd, Member: "a", Score: 3}, ErrMemberExists},
+		{Op{Type: OpAdd, Member: "c", Score: 9}, ErrOutOfRange},
+		{Op{Type: OpIncrement, Member: "a", Score: -2}, ErrOutOfRange},
+		{Op{Type: OpUpdate, Member: "c", Score: 3}, ErrMemberNotFound},
+	}
+	for _, tt := range tests {
+		batch := []Op{{Type: OpIncrement, Member: "b", Score: 5}, {Type: OpRemove, Member: "x"}, tt.op}
+		if _, err := tree.Apply(batch); errors.Is(err, tt.err) == false {
+			t.Errorf("tree.Apply(%v) error = %v, want %v", tt.op, err, tt.err)
+		}
+		checkRankWithScore(t, tree.RangeWithScore(0, -1), []string{"a", "b"}, []int{1, 2})
+	}
+}
+
+
+func TestRankTree_ApplyRandom(t *testing.T) {
+	tree, err := New(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := New(0, 100)
+	r := rand.New(rand.NewSource(1))
+
+	for round := 0; round < 50; round++ {
+		batch := make([]Op, 100)
+		for i := range batch {
+			member := fmt.Sprintf("m%d", r.Intn(60))
+			switch r.Intn(4) {
+			case 0:
+				batch[i] = Op{Type: OpIncrement, Member: member, Score: r.Intn(5)}
+			case 1:
+				batch[i] = Op{Type: OpUpdate, Member: member, Score: r.Intn(90), Insert: true}
+			case 2:
+				batch[i] = Op{Type: OpRemove, Member: member}
+			case 3:
+				batch[i] = Op{Type: OpUpdate, Member: member, Score: r.Intn(20), Insert: true}
+			}
+		}
+
+		if _, err := tree.Apply(batch); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, op := range batch {
+			switch op.Type {
+			case OpIncrement:
+				want.IncrementBy(op.Member, op.Score)
+			case OpUpdate:
+				want.UpdateScore(op.Member, op.Score, op.Insert)
+			case OpRemove:
+				want.Remove(op.Member)
+			}
+		}
+
+		checkRankWithScore(t, tree.RangeWithScore(0, -1), want.Range(0, -1), scoresOf(want.RangeWithScore(0, -1)))
+		checkRankTree(t, tree, 0, 100, want.Card())
+		checkListOrder(t, tree)
+	}
+}
+
+
+func scoresOf(ranks []RankWithScore) []int {
+	scores := make([]int, len(ranks))
+	for i, v := range ranks {
+		scores[i] = v.Score
+	}
+	return scores
+}
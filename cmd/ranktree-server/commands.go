@@ -0,0 +1,548 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// command is a command handler.
+// <arity> is the number of arguments including the command name,
+// or -N if the command takes at least N arguments, like Redis.
+type command struct {
+	fn		func(s *server, c *client, args []string)
+	arity	int
+}
+
+
+var commands map[string]command
+
+
+func init() {
+	commands = map[string]command{
+		"ping":				{ping, -1},
+		"echo":				{echo, 2},
+		"hello":			{hello, -1},
+		"quit":				{quit, 1},
+		"command":			{commandInfo, -1},
+		"zadd":				{zadd, -4},
+		"zincrby":			{zincrby, 4},
+		"zrank":			{zrank, -3},
+		"zrevrank":			{zrevrank, -3},
+		"zscore":			{zscore, 3},
+		"zcard":			{zcard, 2},
+		"zcount":			{zcount, 4},
+		"zrange":			{zrange, -4},
+		"zrevrange":		{zrevrange, -4},
+		"zrangebyscore":	{zrangebyscore, -4},
+		"zpopmax":			{zpopmax, -2},
+		"zpopmin":			{zpopmin, -2},
+		"zrem":				{zrem, -3},
+	}
+}
+
+
+const (
+	errSyntax		= "ERR syntax error"
+	errNotInteger	= "ERR value is not an integer or out of range"
+	errOutOfRange	= "ERR score is out of the range of the tree"
+)
+
+
+// Parses a non-negative integer score.
+func parseScore(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	return n, err == nil && n >= 0
+}
+
+
+// Parses a bound of a score range, i.e. an integer, "(" followed by an integer for an exclusive bound,
+// "-inf" or "+inf". <upper> tells whether it is the upper bound.
+func parseBound(s string, upper bool) (int, bool) {
+	switch strings.ToLower(s) {
+	case "-inf":
+		return -1, true
+	case "+inf", "inf":
+		return math.MaxInt, true
+	}
+
+	exclusive := strings.HasPrefix(s, "(")
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "("))
+	if err != nil {
+		return 0, false
+	}
+
+	if exclusive && upper {
+		n--
+	} else if exclusive && n < math.MaxInt {
+		n++
+	}
+	return n, true
+}
+
+
+func ping(s *server, c *client, args []string) {
+	if len(args) > 2 {
+		c.w.error("ERR wrong number of arguments for 'ping' command")
+	} else if len(args) == 2 {
+		c.w.bulk(args[1])
+	} else {
+		c.w.simple("PONG")
+	}
+}
+
+
+func echo(s *server, c *client, args []string) {
+	c.w.bulk(args[1])
+}
+
+
+// HELLO [protover], switches the protocol and replies the server information.
+func hello(s *server, c *client, args []string) {
+	if len(args) > 1 {
+		proto, err := strconv.Atoi(args[1])
+		if err != nil {
+			c.w.error("ERR Protocol version is not an integer or out of range")
+			return
+		}
+		if proto != 2 && proto != 3 {
+			c.w.error("NOPROTO unsupported protocol version")
+			return
+		}
+		c.w.proto = proto
+	}
+
+	c.w.mapHeader(7)
+	c.w.bulk("server")
+	c.w.bulk("ranktree")
+	c.w.bulk("version")
+	c.w.bulk(version)
+	c.w.bulk("proto")
+	c.w.integer(c.w.proto)
+	c.w.bulk("id")
+	c.w.integer(c.id)
+	c.w.bulk("mode")
+	c.w.bulk("standalone")
+	c.w.bulk("role")
+	c.w.bulk("master")
+	c.w.bulk("modules")
+	c.w.array(0)
+}
+
+
+func quit(s *server, c *client, args []string) {
+	c.w.simple("OK")
+}
+
+
+// COMMAND is sent by some clients on connect, an empty reply is enough for them.
+func commandInfo(s *server, c *client, args []string) {
+	c.w.array(0)
+}
+
+
+// ZADD key [NX|XX] [CH] [INCR] score member [score member ...]
+func zadd(s *server, c *client, args []string) {
+	key := args[1]
+	var nx, xx, ch, incr bool
+
+	i := 2
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "ch":
+			ch = true
+		case "incr":
+			incr = true
+		default:
+			break options
+		}
+	}
+
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs) % 2 != 0 || (nx && xx) || (incr && len(pairs) != 2) {
+		c.w.error(errSyntax)
+		return
+	}
+
+	for j := 0; j < len(pairs); j += 2 {
+		if _, ok := parseScore(pairs[j]); ok == false {
+			c.w.error(errNotInteger)
+			return
+		}
+	}
+
+	tree, err := s.treeForWrite(key)
+	if err != nil {
+		c.w.error("ERR " + err.Error())
+		return
+	}
+	defer s.deleteIfEmpty(key)
+
+	if incr {
+		zincr(c, tree, pairs[1], pairs[0], nx, xx)
+		return
+	}
+
+	// apply all pairs at once, nothing is changed if a score is out of the range
+	var ops []ranktree.Op
+	added, changed := 0, 0
+	scores := make(map[string]int)
+	for j := 0; j < len(pairs); j += 2 {
+		score, _ := parseScore(pairs[j])
+		member := pairs[j + 1]
+
+		old, exists := scores[member]
+		if exists == false {
+			old = tree.Score(member)
+			exists = old != -1
+		}
+		if (nx && exists) || (xx && exists == false) {
+			continue
+		}
+
+		if exists == false {
+			added++
+		}
+		if exists == false || old != score {
+			changed++
+		}
+		scores[member] = score
+		ops = append(ops, ranktree.Op{Type: ranktree.OpUpdate, Member: member, Score: score, Insert: true})
+	}
+
+	if _, err := tree.Apply(ops); err != nil {
+		c.w.error(errOutOfRange)
+		return
+	}
+
+	if ch {
+		c.w.integer(changed)
+	} else {
+		c.w.integer(added)
+	}
+}
+
+
+// ZADD with INCR, replies the new score, or null if NX or XX aborts it.
+func zincr(c *client, tree *ranktree.RankTree, member, increment string, nx, xx bool) {
+	exists := tree.Score(member) != -1
+	if (nx && exists) || (xx && exists == false) {
+		c.w.null()
+		return
+	}
+	incrementBy(c, tree, member, increment)
+}
+
+
+// ZINCRBY key increment member
+func zincrby(s *server, c *client, args []string) {
+	tree, err := s.treeForWrite(args[1])
+	if err != nil {
+		c.w.error("ERR " + err.Error())
+		return
+	}
+	defer s.deleteIfEmpty(args[1])
+
+	incrementBy(c, tree, args[3], args[2])
+}
+
+
+// Increments the score of <member> and replies the new score.
+func incrementBy(c *client, tree *ranktree.RankTree, member, increment string) {
+	n, err := strconv.Atoi(increment)
+	if err != nil {
+		c.w.error(errNotInteger)
+		return
+	}
+
+	results, err := tree.Apply([]ranktree.Op{{Type: ranktree.OpIncrement, Member: member, Score: n}})
+	if err != nil {
+		c.w.error(errOutOfRange)
+		return
+	}
+	c.w.score(results[0].Score)
+}
+
+
+// ZRANK key member [WITHSCORE]
+func zrank(s *server, c *client, args []string) {
+	rank(s, c, args, false)
+}
+
+
+// ZREVRANK key member [WITHSCORE]
+func zrevrank(s *server, c *client, args []string) {
+	rank(s, c, args, true)
+}
+
+
+func rank(s *server, c *client, args []string, reverse bool) {
+	withScore := false
+	if len(args) == 4 && strings.EqualFold(args[3], "withscore") {
+		withScore = true
+	} else if len(args) > 3 {
+		c.w.error(errSyntax)
+		return
+	}
+
+	tree := s.tree(args[1])
+	if tree == nil || tree.Score(args[2]) == -1 {
+		if withScore {
+			c.w.nullArray()
+		} else {
+			c.w.null()
+		}
+		return
+	}
+
+	n := tree.Rank(args[2])
+	if reverse {
+		n = tree.RevRank(args[2])
+	}
+
+	if withScore {
+		c.w.array(2)
+		c.w.integer(n)
+		c.w.score(tree.Score(args[2]))
+	} else {
+		c.w.integer(n)
+	}
+}
+
+
+// ZSCORE key member
+func zscore(s *server, c *client, args []string) {
+	tree := s.tree(args[1])
+	if tree == nil || tree.Score(args[2]) == -1 {
+		c.w.null()
+		return
+	}
+	c.w.score(tree.Score(args[2]))
+}
+
+
+// ZCARD key
+func zcard(s *server, c *client, args []string) {
+	if tree := s.tree(args[1]); tree != nil {
+		c.w.integer(tree.Card())
+	} else {
+		c.w.integer(0)
+	}
+}
+
+
+// ZCOUNT key min max
+func zcount(s *server, c *client, args []string) {
+	min, ok1 := parseBound(args[2], false)
+	max, ok2 := parseBound(args[3], true)
+	if ok1 == false || ok2 == false {
+		c.w.error("ERR min or max is not an integer")
+		return
+	}
+
+	if tree := s.tree(args[1]); tree != nil {
+		c.w.integer(tree.Count(min, max))
+	} else {
+		c.w.integer(0)
+	}
+}
+
+
+// ZRANGE key start stop [REV] [WITHSCORES]
+func zrange(s *server, c *client, args []string) {
+	reverse, withScores := false, false
+	for _, arg := range args[4:] {
+		switch strings.ToLower(arg) {
+		case "rev":
+			reverse = true
+		case "withscores":
+			withScores = true
+		default:
+			c.w.error(errSyntax)
+			return
+		}
+	}
+	rangeByRank(s, c, args, reverse, withScores)
+}
+
+
+// ZREVRANGE key start stop [WITHSCORES]
+func zrevrange(s *server, c *client, args []string) {
+	withScores := false
+	if len(args) == 5 && strings.EqualFold(args[4], "withscores") {
+		withScores = true
+	} else if len(args) > 4 {
+		c.w.error(errSyntax)
+		return
+	}
+	rangeByRank(s, c, args, true, withScores)
+}
+
+
+func rangeByRank(s *server, c *client, args []string, reverse, withScores bool) {
+	start, err1 := strconv.Atoi(args[2])
+	end, err2 := strconv.Atoi(args[3])
+	if err1 != nil || err2 != nil {
+		c.w.error(errNotInteger)
+		return
+	}
+
+	tree := s.tree(args[1])
+	if tree == nil {
+		c.w.array(0)
+		return
+	}
+
+	if reverse {
+		writeRanks(c, tree.RevRangeWithScore(start, end), withScores)
+		return
+	}
+	writeRanks(c, tree.RangeWithScore(start, end), withScores)
+}
+
+
+// ZRANGEBYSCORE key min max [WITHSCORES] [LIMIT offset count]
+func zrangebyscore(s *server, c *client, args []string) {
+	min, ok1 := parseBound(args[2], false)
+	max, ok2 := parseBound(args[3], true)
+	if ok1 == false || ok2 == false {
+		c.w.error("ERR min or max is not an integer")
+		return
+	}
+
+	withScores, offset, count := false, 0, -1
+	for i := 4; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "withscores":
+			withScores = true
+		case "limit":
+			if i + 2 >= len(args) {
+				c.w.error(errSyntax)
+				return
+			}
+			var err1, err2 error
+			offset, err1 = strconv.Atoi(args[i + 1])
+			count, err2 = strconv.Atoi(args[i + 2])
+			if err1 != nil || err2 != nil {
+				c.w.error(errNotInteger)
+				return
+			}
+			i += 2
+		default:
+			c.w.error(errSyntax)
+			return
+		}
+	}
+
+	tree := s.tree(args[1])
+	if tree == nil || offset < 0 || count == 0 {
+		c.w.array(0)
+		return
+	}
+
+	// ranks of the members between min and max are [first, first + Count(min, max))
+	n := tree.Count(min, max)
+	if n == 0 || offset >= n {
+		c.w.array(0)
+		return
+	}
+	if count < 0 || count > n - offset {
+		count = n - offset
+	}
+
+	first := 0
+	if min > 0 {
+		first = tree.Count(0, min - 1)
+	}
+	writeRanks(c, tree.RangeWithScore(first + offset, first + offset + count - 1), withScores)
+}
+
+
+// ZPOPMAX key [count]
+func zpopmax(s *server, c *client, args []string) {
+	pop(s, c, args, true)
+}
+
+
+// ZPOPMIN key [count]
+func zpopmin(s *server, c *client, args []string) {
+	pop(s, c, args, false)
+}
+
+
+func pop(s *server, c *client, args []string, highest bool) {
+	if len(args) > 3 {
+		c.w.error(errSyntax)
+		return
+	}
+
+	count := 1
+	if len(args) == 3 {
+		var err error
+		if count, err = strconv.Atoi(args[2]); err != nil || count < 0 {
+			c.w.error("ERR value is out of range, must be positive")
+			return
+		}
+	}
+
+	tree := s.tree(args[1])
+	if tree == nil {
+		c.w.array(0)
+		return
+	}
+	defer s.deleteIfEmpty(args[1])
+
+	var ranks []ranktree.RankWithScore
+	if highest {
+		ranks = tree.PopMaxN(count)
+	} else {
+		ranks = tree.PopMinN(count)
+	}
+
+	// a single pair is not nested in RESP3 without <count>, like Redis
+	if len(args) == 2 && c.w.proto == 3 && len(ranks) == 1 {
+		c.w.array(2)
+		c.w.bulk(ranks[0].Member)
+		c.w.score(ranks[0].Score)
+		return
+	}
+	writeRanks(c, ranks, true)
+}
+
+
+// ZREM key member [member ...]
+func zrem(s *server, c *client, args []string) {
+	tree := s.tree(args[1])
+	if tree == nil {
+		c.w.integer(0)
+		return
+	}
+	defer s.deleteIfEmpty(args[1])
+
+	c.w.integer(tree.Remove(args[2:]...))
+}
+
+
+// Writes <ranks> as members, or members with scores.
+func writeRanks(c *client, ranks []ranktree.RankWithScore, withScores bool) {
+	members := make([]string, len(ranks))
+	scores := make([]int, len(ranks))
+	for i, v := range ranks {
+		members[i], scores[i] = v.Member, v.Score
+	}
+
+	if withScores {
+		c.w.scorePairs(members, scores)
+	} else {
+		c.w.strings(members)
+	}
+}
+
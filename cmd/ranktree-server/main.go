@@ -0,0 +1,81 @@
+// Command ranktree-server serves named RankTrees over the Redis protocol (RESP2 and RESP3),
+// so that any Redis client can use them as sorted sets.
+//
+// Supported commands: ZADD, ZINCRBY, ZRANK, ZREVRANK, ZSCORE, ZCARD, ZCOUNT, ZRANGE, ZREVRANGE,
+// ZRANGEBYSCORE, ZPOPMAX, ZPOPMIN, ZREM, and PING, ECHO, HELLO, QUIT.
+// Scores must be non-negative integers. Members with equal scores are ordered lexicographically,
+// in ZREVRANK and ZREVRANGE as well, like RankTree.RevRange() (Redis reverses them there).
+//
+// Usage:
+//
+//	ranktree-server [-addr 127.0.0.1:6380] [-backend skiplist] [-low 0] [-high N] [-autoextend]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+const version = "1.0.0"
+
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6380", "address to listen on")
+	backend := flag.String("backend", "skiplist", "backend of the trees: segment, fenwick or skiplist")
+	low := flag.Int("low", 0, "lower bound of the score range of the trees")
+	high := flag.Int("high", math.MaxInt, "upper bound of the score range of the trees")
+	autoExtend := flag.Bool("autoextend", false, "extend the score range of the trees on demand")
+	flag.Parse()
+
+	newTree, err := treeFactory(*backend, *low, *high, *autoExtend)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("ranktree-server %s listening on %s", version, l.Addr())
+
+	if err := newServer(newTree).serve(l); err != nil {
+		log.Fatal(err)
+	}
+}
+
+
+// Returns a function which creates the tree of a new key.
+func treeFactory(backend string, low, high int, autoExtend bool) (func() (*ranktree.RankTree, error), error) {
+	var opts []ranktree.Option
+	switch backend {
+	case "segment":
+	case "fenwick":
+		opts = append(opts, ranktree.WithFenwick())
+	case "skiplist":
+		opts = append(opts, ranktree.WithSkipList())
+	default:
+		return nil, fmt.Errorf("unknown backend %q", backend)
+	}
+
+	if autoExtend {
+		opts = append(opts, ranktree.WithAutoExtend())
+	}
+
+	// fenwick fails with ErrRangeTooLarge, the dense backends also when extending on demand,
+	// New() stores a huge range of the default segment tree in a skip list
+	if _, err := ranktree.New(low, high, opts...); err != nil {
+		return nil, fmt.Errorf("%s backend: %w", backend, err)
+	}
+	return func() (*ranktree.RankTree, error) {
+		return ranktree.New(low, high, opts...)
+	}, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+
+// Limits of a request, like Redis.
+const (
+	maxArgs		= 1024 * 1024
+	maxBulkLen	= 512 * 1024 * 1024
+	maxInline	= 64 * 1024
+)
+
+
+var errProtocol = errors.New("protocol error")
+
+
+// respReader reads commands sent by clients.
+type respReader struct {
+	r	*bufio.Reader
+}
+
+
+func newRespReader(r io.Reader) *respReader {
+	return &respReader{bufio.NewReader(r)}
+}
+
+
+// Reads a line without the trailing CRLF.
+func (r *respReader) readLine() (string, error) {
+	line, err := r.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) > maxInline {
+		return "", errProtocol
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+
+// Reads a command, either an array of bulk strings or an inline command.
+// An empty command is returned for an empty line.
+func (r *respReader) readCommand() ([]string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 || line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > maxArgs {
+		return nil, errProtocol
+	}
+
+	args := make([]string, 0, max(n, 0))
+	for i := 0; i < n; i++ {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > maxBulkLen {
+			return nil, errProtocol
+		}
+
+		buf := make([]byte, size + 2)
+		if _, err := io.ReadFull(r.r, buf); err != nil {
+			return nil, err
+		}
+		if buf[size] != '\r' || buf[size + 1] != '\n' {
+			return nil, errProtocol
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+
+// respWriter writes replies in RESP2 or RESP3.
+type respWriter struct {
+	w		*bufio.Writer
+	proto	int		// 2 or 3, see HELLO
+}
+
+
+func newRespWriter(w io.Writer) *respWriter {
+	return &respWriter{w: bufio.NewWriter(w), proto: 2}
+}
+
+
+func (w *respWriter) flush() error {
+	return w.w.Flush()
+}
+
+
+func (w *respWriter) simple(s string) {
+	fmt.Fprintf(w.w, "+%s\r\n", s)
+}
+
+
+// Writes an error reply, CR and LF in <msg>, e.g. from the arguments of a client, are replaced by spaces like Redis.
+func (w *respWriter) error(msg string) {
+	msg = strings.NewReplacer("\r", " ", "\n", " ").Replace(msg)
+	fmt.Fprintf(w.w, "-%s\r\n", msg)
+}
+
+
+func (w *respWriter) integer(n int) {
+	fmt.Fprintf(w.w, ":%d\r\n", n)
+}
+
+
+func (w *respWriter) bulk(s string) {
+	fmt.Fprintf(w.w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+
+// Writes a null bulk string in RESP2, or a null in RESP3.
+func (w *respWriter) null() {
+	if w.proto == 3 {
+		w.w.WriteString("_\r\n")
+	} else {
+		w.w.WriteString("$-1\r\n")
+	}
+}
+
+
+// Writes a null array in RESP2, or a null in RESP3.
+func (w *respWriter) nullArray() {
+	if w.proto == 3 {
+		w.w.WriteString("_\r\n")
+	} else {
+		w.w.WriteString("*-1\r\n")
+	}
+}
+
+
+// Writes the header of an array of <n> elements.
+func (w *respWriter) array(n int) {
+	fmt.Fprintf(w.w, "*%d\r\n", n)
+}
+
+
+// Writes the header of a map of <n> pairs, which is an array of 2n elements in RESP2.
+func (w *respWriter) mapHeader(n int) {
+	if w.proto == 3 {
+		fmt.Fprintf(w.w, "%%%d\r\n", n)
+	} else {
+		w.array(2 * n)
+	}
+}
+
+
+// Writes a score, which is a double in RESP3, or a bulk string in RESP2.
+func (w *respWriter) score(n int) {
+	if w.proto == 3 {
+		fmt.Fprintf(w.w, ",%d\r\n", n)
+	} else {
+		w.bulk(strconv.Itoa(n))
+	}
+}
+
+
+// Writes members with their scores, as an array of [member, score] pairs in RESP3,
+// or a flat array of members and scores in RESP2.
+func (w *respWriter) scorePairs(members []string, scores []int) {
+	if w.proto == 3 {
+		w.array(len(members))
+		for i, member := range members {
+			w.array(2)
+			w.bulk(member)
+			w.score(scores[i])
+		}
+		return
+	}
+
+	w.array(2 * len(members))
+	for i, member := range members {
+		w.bulk(member)
+		w.score(scores[i])
+	}
+}
+
+
+func (w *respWriter) strings(values []string) {
+	w.array(len(values))
+	for _, v := range values {
+		w.bulk(v)
+	}
+}
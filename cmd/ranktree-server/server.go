@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// server serves the sorted-set commands of Redis over RESP, backed by named RankTrees.
+// Commands are executed one at a time under <mu>.
+type server struct {
+	mu		sync.Mutex
+	trees	map[string]*ranktree.RankTree		// key to tree, empty trees are deleted like Redis
+	newTree	func() (*ranktree.RankTree, error)	// creates the tree of a new key
+	nextID	int									// client id, see HELLO
+
+	connMu	sync.Mutex
+	conns	map[net.Conn]bool
+	closed	bool
+}
+
+
+func newServer(newTree func() (*ranktree.RankTree, error)) *server {
+	return &server{
+		trees: make(map[string]*ranktree.RankTree),
+		newTree: newTree,
+		conns: make(map[net.Conn]bool),
+	}
+}
+
+
+// Accepts connections on <l> until it is closed or the server is closed.
+func (s *server) serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			return err
+		}
+
+		if s.track(conn) == false {
+			conn.Close()
+			return nil
+		}
+		go s.serveConn(conn)
+	}
+}
+
+
+// Closes all the connections, serve() returns after its listener is closed.
+func (s *server) close() {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+
+func (s *server) isClosed() bool {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	return s.closed
+}
+
+
+// Adds <conn> to the open connections, returns false if the server is closed.
+func (s *server) track(conn net.Conn) bool {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = true
+	return true
+}
+
+
+// Reads commands from <conn> and writes the replies, until the client quits or an error occurs.
+func (s *server) serveConn(conn net.Conn) {
+	defer func() {
+		s.connMu.Lock()
+		delete(s.conns, conn)
+		s.connMu.Unlock()
+		conn.Close()
+	}()
+
+	s.mu.Lock()
+	s.nextID++
+	c := &client{id: s.nextID, r: newRespReader(conn), w: newRespWriter(conn)}
+	s.mu.Unlock()
+
+	for {
+		args, err := c.r.readCommand()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				c.w.error("ERR Protocol error")
+				c.w.flush()
+			} else if err != io.EOF && s.isClosed() == false {
+				log.Printf("client %d: %v", c.id, err)
+			}
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := s.execute(c, args)
+		if err := c.w.flush(); err != nil || quit {
+			return
+		}
+	}
+}
+
+
+// client is the state of a connection.
+type client struct {
+	id	int
+	r	*respReader
+	w	*respWriter
+}
+
+
+// Executes a command and writes the reply.
+// Returns true if the connection should be closed.
+func (s *server) execute(c *client, args []string) (quit bool) {
+	name := strings.ToLower(args[0])
+	cmd, ok := commands[name]
+	if ok == false {
+		c.w.error("ERR unknown command '" + args[0] + "'")
+		return false
+	}
+
+	if (cmd.arity > 0 && len(args) != cmd.arity) || len(args) < -cmd.arity {
+		c.w.error("ERR wrong number of arguments for '" + name + "' command")
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd.fn(s, c, args)
+	return name == "quit"
+}
+
+
+// Returns the tree of <key>, nil if it does not exist.
+func (s *server) tree(key string) *ranktree.RankTree {
+	return s.trees[key]
+}
+
+
+// Returns the tree of <key>, it is created if it does not exist.
+func (s *server) treeForWrite(key string) (*ranktree.RankTree, error) {
+	if tree, ok := s.trees[key]; ok {
+		return tree, nil
+	}
+
+	tree, err := s.newTree()
+	if err != nil {
+		return nil, err
+	}
+	s.trees[key] = tree
+	return tree, nil
+}
+
+
+// Deletes <key> if its tree is empty.
+func (s *server) deleteIfEmpty(key string) {
+	if tree, ok := s.trees[key]; ok && tree.Card() == 0 {
+		delete(s.trees, key)
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+
+// testClient is a minimal Redis client, replies are decoded into
+// string, int64, float64, nil, []any, or error for error replies.
+type testClient struct {
+	t		*testing.T
+	conn	net.Conn
+	r		*bufio.Reader
+}
+
+
+func (c *testClient) do(args ...string) any {
+	c.t.Helper()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		c.t.Fatal(err)
+	}
+
+	reply, err := c.read()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return reply
+}
+
+
+func (c *testClient) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return fmt.Errorf("%s", line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case ',':
+		return strconv.ParseFloat(line[1:], 64)
+	case '_':
+		return nil, nil
+	case '$':
+		n, _ := strconv.Atoi(line[1:])
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n + 2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*', '%':
+		n, _ := strconv.Atoi(line[1:])
+		if n < 0 {
+			return nil, nil
+		}
+		if line[0] == '%' {
+			n *= 2
+		}
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unexpected reply %q", line)
+}
+
+
+// Starts a server on a loopback listener, returns a function connecting a client to it.
+func startServer(t *testing.T) func() *testClient {
+	newTree, err := treeFactory("skiplist", 0, 1000, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newServer(newTree)
+	done := make(chan error)
+	go func() { done <- s.serve(l) }()
+	t.Cleanup(func() {
+		s.close()
+		l.Close()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+
+	return func() *testClient {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return &testClient{t, conn, bufio.NewReader(conn)}
+	}
+}
+
+
+func isError(v any) bool {
+	_, ok := v.(error)
+	return ok
+}
+
+
+func TestServer(t *testing.T) {
+	c := startServer(t)()
+
+	tests := []struct {
+		args	[]string
+		want	any
+	}{
+		{[]string{"PING"}, "PONG"},
+		{[]string{"echo", "hi"}, "hi"},
+		{[]string{"ZADD", "board", "10", "alice", "20", "bob", "30", "charles"}, int64(3)},
+		{[]string{"ZADD", "board", "NX", "40", "alice", "40", "dave"}, int64(1)},
+		{[]string{"ZADD", "board", "XX", "CH", "15", "alice", "50", "erin"}, int64(1)},
+		{[]string{"ZADD", "board", "INCR", "5", "alice"}, "20"},
+		{[]string{"ZADD", "board", "NX", "INCR", "5", "alice"}, nil},
+		{[]string{"ZINCRBY", "board", "-2", "bob"}, "18"},
+		{[]string{"ZSCORE", "board", "alice"}, "20"},
+		{[]string{"ZSCORE", "board", "nobody"}, nil},
+		{[]string{"ZCARD", "board"}, int64(4)},
+		{[]string{"ZCARD", "nokey"}, int64(0)},
+		{[]string{"ZRANK", "board", "bob"}, int64(0)},
+		{[]string{"ZREVRANK", "board", "dave"}, int64(0)},
+		{[]string{"ZRANK", "board", "alice", "WITHSCORE"}, []any{int64(1), "20"}},
+		{[]string{"ZRANK", "board", "nobody"}, nil},
+		{[]string{"ZCOUNT", "board", "-inf", "+inf"}, int64(4)},
+		{[]string{"ZCOUNT", "board", "(18", "30"}, int64(2)},
+		{[]string{"ZRANGE", "board", "0", "-1"}, []any{"bob", "alice", "charles", "dave"}},
+		{[]string{"ZRANGE", "board", "0", "1", "REV", "WITHSCORES"}, []any{"dave", "40", "charles", "30"}},
+		{[]string{"ZREVRANGE", "board", "1", "2"}, []any{"charles", "alice"}},
+		{[]string{"ZRANGEBYSCORE", "board", "20", "+inf", "WITHSCORES", "LIMIT", "1", "5"}, []any{"charles", "30", "dave", "40"}},
+		{[]string{"ZRANGEBYSCORE", "board", "(40", "+inf"}, []any{}},
+		{[]string{"ZPOPMAX", "board"}, []any{"dave", "40"}},
+		{[]string{"ZPOPMIN", "board", "2"}, []any{"bob", "18", "alice", "20"}},
+		{[]string{"ZREM", "board", "charles", "nobody"}, int64(1)},
+		{[]string{"ZCARD", "board"}, int64(0)},
+		{[]string{"ZPOPMIN", "board"}, []any{}},
+	}
+
+	for _, test := range tests {
+		if got := c.do(test.args...); reflect.DeepEqual(got, test.want) == false {
+			t.Errorf("%v = %#v, want %#v", test.args, got, test.want)
+		}
+	}
+}
+
+
+func TestServer_Ties(t *testing.T) {
+	c := startServer(t)()
+	c.do("ZADD", "board", "10", "b", "20", "a", "20", "c", "20", "b2", "30", "d")
+
+	// equal scores are in lexicographical order, in ZREVRANK and ZREVRANGE as well
+	tests := []struct {
+		args	[]string
+		want	any
+	}{
+		{[]string{"ZRANGE", "board", "1", "3"}, []any{"a", "b2", "c"}},
+		{[]string{"ZRANK", "board", "c"}, int64(3)},
+		{[]string{"ZREVRANGE", "board", "0", "-1"}, []any{"d", "a", "b2", "c", "b"}},
+		{[]string{"ZREVRANGE", "board", "-4", "2", "WITHSCORES"}, []any{"a", "20", "b2", "20"}},
+		{[]string{"ZRANGE", "board", "1", "1", "REV"}, []any{"a"}},
+		{[]string{"ZREVRANGE", "board", "3", "1"}, []any{}},
+		{[]string{"ZREVRANGE", "board", "4", "10"}, []any{"b"}},
+		{[]string{"ZREVRANK", "board", "a"}, int64(1)},
+		{[]string{"ZREVRANK", "board", "b2"}, int64(2)},
+		{[]string{"ZREVRANK", "board", "c"}, int64(3)},
+		{[]string{"ZPOPMAX", "board", "2"}, []any{"d", "30", "c", "20"}},
+	}
+
+	for _, test := range tests {
+		if got := c.do(test.args...); reflect.DeepEqual(got, test.want) == false {
+			t.Errorf("%v = %#v, want %#v", test.args, got, test.want)
+		}
+	}
+}
+
+
+func TestServer_Errors(t *testing.T) {
+	c := startServer(t)()
+	c.do("ZADD", "board", "10", "alice")
+
+	tests := [][]string{
+		{"NOSUCHCOMMAND"},
+		{"ZCARD"},
+		{"ZADD", "board", "10"},
+		{"ZADD", "board", "NX", "XX", "10", "bob"},
+		{"ZADD", "board", "ten", "bob"},
+		{"ZADD", "board", "-1", "bob"},
+		{"ZADD", "board", "10", "bob", "2000", "charles"},
+		{"ZINCRBY", "board", "2000", "alice"},
+		{"ZCOUNT", "board", "a", "b"},
+		{"ZRANGE", "board", "0", "-1", "BYLEX"},
+		{"HELLO", "4"},
+	}
+
+	for _, args := range tests {
+		if got := c.do(args...); isError(got) == false {
+			t.Errorf("%v = %#v, want an error", args, got)
+		}
+	}
+
+	// CR and LF of the arguments do not end the error reply
+	if got := c.do("NO\r\n+OK"); fmt.Sprint(got) != "ERR unknown command 'NO  +OK'" {
+		t.Errorf("unknown command with CRLF = %#v", got)
+	}
+	if got := c.do("PING"); got != "PONG" {
+		t.Errorf("PING after an error with CRLF = %#v", got)
+	}
+
+	// a failed ZADD changes nothing
+	if got := c.do("ZRANGE", "board", "0", "-1", "WITHSCORES"); reflect.DeepEqual(got, []any{"alice", "10"}) == false {
+		t.Errorf("ZRANGE = %#v", got)
+	}
+	if got := c.do("ZCARD", "nokey"); got != int64(0) {
+		t.Errorf("ZCARD nokey = %#v", got)
+	}
+}
+
+
+func TestServer_RESP3(t *testing.T) {
+	c := startServer(t)()
+
+	hello, ok := c.do("HELLO", "3").([]any)
+	if ok == false || len(hello) != 14 || hello[4] != "proto" || hello[5] != int64(3) {
+		t.Fatalf("HELLO 3 = %#v", hello)
+	}
+
+	c.do("ZADD", "board", "10", "alice", "20", "bob")
+
+	tests := []struct {
+		args	[]string
+		want	any
+	}{
+		{[]string{"ZSCORE", "board", "bob"}, float64(20)},
+		{[]string{"ZSCORE", "board", "nobody"}, nil},
+		{[]string{"ZRANGE", "board", "0", "-1", "WITHSCORES"}, []any{[]any{"alice", float64(10)}, []any{"bob", float64(20)}}},
+		{[]string{"ZPOPMAX", "board"}, []any{"bob", float64(20)}},
+		{[]string{"ZPOPMIN", "board", "1"}, []any{[]any{"alice", float64(10)}}},
+	}
+
+	for _, test := range tests {
+		if got := c.do(test.args...); reflect.DeepEqual(got, test.want) == false {
+			t.Errorf("%v = %#v, want %#v", test.args, got, test.want)
+		}
+	}
+}
+
+
+func TestServer_Clients(t *testing.T) {
+	connect := startServer(t)
+	c1, c2 := connect(), connect()
+
+	c1.do("ZADD", "board", "10", "alice")
+	if got := c2.do("ZSCORE", "board", "alice"); got != "10" {
+		t.Errorf("ZSCORE from another client = %#v", got)
+	}
+
+	// inline commands, like redis-cli or telnet
+	fmt.Fprintf(c2.conn, "ZCARD board\r\n")
+	if got, err := c2.read(); err != nil || got != int64(1) {
+		t.Errorf("inline ZCARD = %#v, %v", got, err)
+	}
+
+	if got := c1.do("QUIT"); got != "OK" {
+		t.Errorf("QUIT = %#v", got)
+	}
+	if _, err := c1.read(); err == nil {
+		t.Error("connection is not closed after QUIT")
+	}
+}
+
+
+func TestTreeFactory(t *testing.T) {
+	if _, err := treeFactory("btree", 0, 100, false); err == nil {
+		t.Error("unknown backend is accepted")
+	}
+	if _, err := treeFactory("fenwick", 0, 1 << 40, false); err == nil {
+		t.Error("huge dense range is accepted")
+	}
+	if _, err := treeFactory("segment", 0, 1 << 40, false); err != nil {
+		t.Errorf("huge range of the default backend: %v", err)
+	}
+
+	newTree, err := treeFactory("fenwick", 0, 100, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := newTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Add("alice", 1000)
+	if tree.Score("alice") != 1000 {
+		t.Error("-autoextend is ignored")
+	}
+
+	// a dense backend is not extended to a huge range on demand
+	if tree.Add("bob", 1 << 40) || tree.Score("bob") != -1 || tree.Score("alice") != 1000 {
+		t.Error("-autoextend extended a dense backend to a huge range")
+	}
+}
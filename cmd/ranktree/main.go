@@ -0,0 +1,107 @@
+// Command ranktree inspects and edits a RankTree file written by RankTree.WriteTo().
+//
+// It loads the file, or starts an empty tree if the file does not exist,
+// then reads Redis-style commands without the key, e.g. "ZADD 10 alice" or "ZREVRANGE 0 9 WITHSCORES",
+// from the standard input. TREE prints the structure of the tree, SAVE writes it back.
+// Type HELP for the list of commands.
+//
+// Usage:
+//
+//	ranktree [-backend skiplist] [-low 0] [-high N] file
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+func main() {
+	backend := flag.String("backend", "skiplist", "backend of the tree: segment, fenwick or skiplist")
+	low := flag.Int("low", 0, "lower bound of the score range of a new tree")
+	high := flag.Int("high", math.MaxInt, "upper bound of the score range of a new tree")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] file\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	file := flag.Arg(0)
+
+	opts, err := backendOptions(*backend)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	tree, err := load(file, opts, *low, *high)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// prompt only for a terminal
+	prompt := ""
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode() & os.ModeCharDevice != 0 {
+		prompt = "ranktree> "
+	}
+
+	r := &repl{tree: tree, file: file, out: os.Stdout}
+	if err := r.run(os.Stdin, prompt); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+
+// Returns the options of <backend>.
+func backendOptions(backend string) ([]ranktree.Option, error) {
+	switch backend {
+	case "segment":
+		return nil, nil
+	case "fenwick":
+		return []ranktree.Option{ranktree.WithFenwick()}, nil
+	case "skiplist":
+		return []ranktree.Option{ranktree.WithSkipList()}, nil
+	}
+	return nil, fmt.Errorf("unknown backend %q", backend)
+}
+
+
+// Loads the tree from <file>, or creates an empty tree covering [low, high] if it does not exist.
+func load(file string, opts []ranktree.Option, low, high int) (*ranktree.RankTree, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		tree, err := ranktree.New(low, high, opts...)
+		return tree, rangeHint(err)
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Load() checks the range of the header before reading the members
+	tree, err := ranktree.Load(f, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, rangeHint(err))
+	}
+	return tree, nil
+}
+
+
+// Returns <err> with a hint if the range is too large for a dense backend.
+func rangeHint(err error) error {
+	if errors.Is(err, ranktree.ErrRangeTooLarge) {
+		return fmt.Errorf("%w, use -backend skiplist", err)
+	}
+	return err
+}
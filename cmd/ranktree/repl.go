@@ -0,0 +1,542 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// repl executes commands on a tree, replies are written like redis-cli.
+type repl struct {
+	tree		*ranktree.RankTree
+	file		string		// file of SAVE without an argument
+	out			io.Writer
+	dirty		bool		// changed since loaded or saved
+	quitting	bool		// QUIT with unsaved changes was entered
+}
+
+
+// command is a command handler.
+// <arity> is the number of arguments including the command name,
+// or -N if the command takes at least N arguments, like Redis.
+type command struct {
+	fn		func(r *repl, args []string) error
+	arity	int
+	usage	string
+}
+
+
+var commands map[string]command
+
+
+func init() {
+	commands = map[string]command{
+		"zadd":				{zadd, -3, "ZADD score member [score member ...]"},
+		"zincrby":			{zincrby, 3, "ZINCRBY increment member"},
+		"zrem":				{zrem, -2, "ZREM member [member ...]"},
+		"zpopmax":			{zpopmax, -1, "ZPOPMAX [count]"},
+		"zpopmin":			{zpopmin, -1, "ZPOPMIN [count]"},
+		"zscore":			{zscore, 2, "ZSCORE member"},
+		"zrank":			{zrank, 2, "ZRANK member"},
+		"zrevrank":			{zrevrank, 2, "ZREVRANK member"},
+		"zcard":			{zcard, 1, "ZCARD"},
+		"zcount":			{zcount, 3, "ZCOUNT min max"},
+		"zrange":			{zrange, -3, "ZRANGE start stop [WITHSCORES]"},
+		"zrevrange":		{zrevrange, -3, "ZREVRANGE start stop [WITHSCORES]"},
+		"zrangebyscore":	{zrangebyscore, -3, "ZRANGEBYSCORE min max [WITHSCORES]"},
+		"tree":				{printTree, 1, "TREE, prints the structure of the tree"},
+		"info":				{info, 1, "INFO"},
+		"save":				{save, -1, "SAVE [file]"},
+		"help":				{help, 1, "HELP"},
+		"quit":				{quit, 1, "QUIT, asks again if there are unsaved changes"},
+		"exit":				{quit, 1, "EXIT, the same as QUIT"},
+	}
+}
+
+
+// errQuit is returned by QUIT to stop the loop.
+var errQuit = errors.New("quit")
+
+
+// Reads commands from <in> and executes them, until EOF or QUIT.
+// <prompt> is written before each command, it is empty if <in> is not a terminal.
+func (r *repl) run(in io.Reader, prompt string) error {
+	cancel := r.tree.OnChange(func(ranktree.Event) {
+		r.dirty = true
+	})
+	defer cancel()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1 << 20)
+
+	for {
+		fmt.Fprint(r.out, prompt)
+		if scanner.Scan() == false {
+			if prompt != "" {
+				fmt.Fprintln(r.out)
+			}
+			return scanner.Err()
+		}
+
+		args, err := splitArgs(scanner.Text())
+		if err != nil {
+			fmt.Fprintf(r.out, "(error) ERR %v\n", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		if err := r.execute(args); err == errQuit {
+			return nil
+		} else if err != nil {
+			fmt.Fprintf(r.out, "(error) %v\n", err)
+		}
+	}
+}
+
+
+// Executes a command.
+func (r *repl) execute(args []string) error {
+	name := strings.ToLower(args[0])
+	cmd, ok := commands[name]
+	if ok == false {
+		return fmt.Errorf("ERR unknown command '%s', type HELP for the commands", args[0])
+	}
+
+	if (cmd.arity > 0 && len(args) != cmd.arity) || len(args) < -cmd.arity {
+		return fmt.Errorf("ERR wrong number of arguments, usage: %s", cmd.usage)
+	}
+
+	if name != "quit" && name != "exit" {
+		r.quitting = false
+	}
+	return cmd.fn(r, args)
+}
+
+
+// Splits <line> into arguments separated by spaces.
+// An argument may be double-quoted as a Go string literal, or single-quoted without escapes.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return args, nil
+		}
+
+		var arg string
+		switch line[0] {
+		case '"':
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, errors.New("unbalanced quotes")
+			}
+			arg, _ = strconv.Unquote(quoted)
+			line = line[len(quoted):]
+		case '\'':
+			end := strings.IndexByte(line[1:], '\'')
+			if end == -1 {
+				return nil, errors.New("unbalanced quotes")
+			}
+			arg, line = line[1:end + 1], line[end + 2:]
+		default:
+			end := strings.IndexAny(line, " \t")
+			if end == -1 {
+				end = len(line)
+			}
+			arg, line = line[:end], line[end:]
+		}
+
+		if line != "" && line[0] != ' ' && line[0] != '\t' {
+			return nil, errors.New("closing quote must be followed by a space")
+		}
+		args = append(args, arg)
+	}
+}
+
+
+// Replies of commands.
+
+func (r *repl) integer(n int) {
+	fmt.Fprintf(r.out, "(integer) %d\n", n)
+}
+
+
+func (r *repl) str(s string) {
+	fmt.Fprintln(r.out, strconv.Quote(s))
+}
+
+
+func (r *repl) null() {
+	fmt.Fprintln(r.out, "(nil)")
+}
+
+
+func (r *repl) ok() {
+	fmt.Fprintln(r.out, "OK")
+}
+
+
+// Writes members, and their scores if <withScores> is true, as a numbered list.
+func (r *repl) ranks(ranks []ranktree.RankWithScore, withScores bool) {
+	if len(ranks) == 0 {
+		fmt.Fprintln(r.out, "(empty array)")
+		return
+	}
+
+	n := 1
+	for _, v := range ranks {
+		fmt.Fprintf(r.out, "%d) %q\n", n, v.Member)
+		n++
+		if withScores {
+			fmt.Fprintf(r.out, "%d) \"%d\"\n", n, v.Score)
+			n++
+		}
+	}
+}
+
+
+var (
+	errNotInteger	= errors.New("ERR value is not an integer or out of range")
+	errOutOfRange	= errors.New("ERR score is out of the range of the tree")
+	errSyntax		= errors.New("ERR syntax error")
+)
+
+
+// Parses a non-negative integer score.
+func parseScore(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, errNotInteger
+	}
+	return n, nil
+}
+
+
+// Parses a bound of a score range, i.e. an integer, "(" followed by an integer for an exclusive bound,
+// "-inf" or "+inf". <upper> tells whether it is the upper bound.
+func parseBound(s string, upper bool) (int, error) {
+	switch strings.ToLower(s) {
+	case "-inf":
+		return -1, nil
+	case "+inf", "inf":
+		return math.MaxInt, nil
+	}
+
+	exclusive := strings.HasPrefix(s, "(")
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "("))
+	if err != nil {
+		return 0, errors.New("ERR min or max is not an integer")
+	}
+
+	if exclusive && upper {
+		n--
+	} else if exclusive && n < math.MaxInt {
+		n++
+	}
+	return n, nil
+}
+
+
+// Returns whether the optional argument at <i> is WITHSCORES, it is a syntax error if it is something else.
+func withScores(args []string, i int) (bool, error) {
+	if len(args) <= i {
+		return false, nil
+	}
+	if len(args) == i + 1 && strings.EqualFold(args[i], "withscores") {
+		return true, nil
+	}
+	return false, errSyntax
+}
+
+
+// ZADD score member [score member ...], all or none of the pairs are added.
+func zadd(r *repl, args []string) error {
+	pairs := args[1:]
+	if len(pairs) % 2 != 0 {
+		return errSyntax
+	}
+
+	var ops []ranktree.Op
+	added := make(map[string]bool)
+	for i := 0; i < len(pairs); i += 2 {
+		score, err := parseScore(pairs[i])
+		if err != nil {
+			return err
+		}
+
+		member := pairs[i + 1]
+		if r.tree.Score(member) == -1 {
+			added[member] = true
+		}
+		ops = append(ops, ranktree.Op{Type: ranktree.OpUpdate, Member: member, Score: score, Insert: true})
+	}
+
+	if _, err := r.tree.Apply(ops); err != nil {
+		return errOutOfRange
+	}
+	r.integer(len(added))
+	return nil
+}
+
+
+// ZINCRBY increment member
+func zincrby(r *repl, args []string) error {
+	n, err := strconv.Atoi(args[1])
+	if err != nil {
+		return errNotInteger
+	}
+
+	results, err := r.tree.Apply([]ranktree.Op{{Type: ranktree.OpIncrement, Member: args[2], Score: n}})
+	if err != nil {
+		return errOutOfRange
+	}
+	r.str(strconv.Itoa(results[0].Score))
+	return nil
+}
+
+
+// ZREM member [member ...]
+func zrem(r *repl, args []string) error {
+	r.integer(r.tree.Remove(args[1:]...))
+	return nil
+}
+
+
+// ZPOPMAX [count]
+func zpopmax(r *repl, args []string) error {
+	return pop(r, args, true)
+}
+
+
+// ZPOPMIN [count]
+func zpopmin(r *repl, args []string) error {
+	return pop(r, args, false)
+}
+
+
+func pop(r *repl, args []string, highest bool) error {
+	if len(args) > 2 {
+		return errSyntax
+	}
+
+	count := 1
+	if len(args) == 2 {
+		var err error
+		if count, err = strconv.Atoi(args[1]); err != nil || count < 0 {
+			return errors.New("ERR value is out of range, must be positive")
+		}
+	}
+
+	if highest {
+		r.ranks(r.tree.PopMaxN(count), true)
+	} else {
+		r.ranks(r.tree.PopMinN(count), true)
+	}
+	return nil
+}
+
+
+// ZSCORE member
+func zscore(r *repl, args []string) error {
+	if score := r.tree.Score(args[1]); score != -1 {
+		r.str(strconv.Itoa(score))
+	} else {
+		r.null()
+	}
+	return nil
+}
+
+
+// ZRANK member
+func zrank(r *repl, args []string) error {
+	if r.tree.Score(args[1]) != -1 {
+		r.integer(r.tree.Rank(args[1]))
+	} else {
+		r.null()
+	}
+	return nil
+}
+
+
+// ZREVRANK member
+func zrevrank(r *repl, args []string) error {
+	if r.tree.Score(args[1]) != -1 {
+		r.integer(r.tree.RevRank(args[1]))
+	} else {
+		r.null()
+	}
+	return nil
+}
+
+
+// ZCARD
+func zcard(r *repl, args []string) error {
+	r.integer(r.tree.Card())
+	return nil
+}
+
+
+// ZCOUNT min max
+func zcount(r *repl, args []string) error {
+	min, err := parseBound(args[1], false)
+	if err != nil {
+		return err
+	}
+	max, err := parseBound(args[2], true)
+	if err != nil {
+		return err
+	}
+
+	r.integer(r.tree.Count(min, max))
+	return nil
+}
+
+
+// ZRANGE start stop [WITHSCORES]
+func zrange(r *repl, args []string) error {
+	return rangeByRank(r, args, false)
+}
+
+
+// ZREVRANGE start stop [WITHSCORES]
+func zrevrange(r *repl, args []string) error {
+	return rangeByRank(r, args, true)
+}
+
+
+func rangeByRank(r *repl, args []string, reverse bool) error {
+	start, err1 := strconv.Atoi(args[1])
+	end, err2 := strconv.Atoi(args[2])
+	if err1 != nil || err2 != nil {
+		return errNotInteger
+	}
+
+	scores, err := withScores(args, 3)
+	if err != nil {
+		return err
+	}
+
+	if reverse {
+		r.ranks(r.tree.RevRangeWithScore(start, end), scores)
+	} else {
+		r.ranks(r.tree.RangeWithScore(start, end), scores)
+	}
+	return nil
+}
+
+
+// ZRANGEBYSCORE min max [WITHSCORES]
+func zrangebyscore(r *repl, args []string) error {
+	min, err := parseBound(args[1], false)
+	if err != nil {
+		return err
+	}
+	max, err := parseBound(args[2], true)
+	if err != nil {
+		return err
+	}
+
+	scores, err := withScores(args, 3)
+	if err != nil {
+		return err
+	}
+
+	r.ranks(r.tree.RangeByScore(min, max), scores)
+	return nil
+}
+
+
+// TREE
+func printTree(r *repl, args []string) error {
+	return r.tree.Print(r.out)
+}
+
+
+// INFO
+func info(r *repl, args []string) error {
+	fmt.Fprintf(r.out, "file: %s\nmembers: %d\n", r.file, r.tree.Card())
+	if r.tree.Card() > 0 {
+		fmt.Fprintf(r.out, "scores: [%d, %d]\n", r.tree.ScoreAtRank(0), r.tree.ScoreAtRevRank(0))
+	}
+	fmt.Fprintf(r.out, "unsaved changes: %v\n", r.dirty)
+	return nil
+}
+
+
+// SAVE [file], the file is replaced atomically.
+func save(r *repl, args []string) error {
+	if len(args) > 2 {
+		return errSyntax
+	}
+
+	file := r.file
+	if len(args) == 2 {
+		file = args[1]
+	}
+
+	if err := writeFile(file, r.tree); err != nil {
+		return fmt.Errorf("ERR %v", err)
+	}
+
+	if file == r.file {
+		r.dirty = false
+	}
+	r.ok()
+	return nil
+}
+
+
+// Writes <tree> to a temporary file, then renames it to <file>.
+func writeFile(file string, tree *ranktree.RankTree) error {
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file) + ".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := tree.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
+
+
+// HELP
+func help(r *repl, args []string) error {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		fmt.Fprintln(r.out, commands[name].usage)
+	}
+	return nil
+}
+
+
+// QUIT
+func quit(r *repl, args []string) error {
+	if r.dirty && r.quitting == false {
+		r.quitting = true
+		return errors.New("ERR there are unsaved changes, SAVE them or QUIT again to discard them")
+	}
+	return errQuit
+}
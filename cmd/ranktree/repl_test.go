@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// Runs <script> on <tree>, returns the output.
+func runScript(t *testing.T, r *repl, script string) string {
+	t.Helper()
+
+	var out strings.Builder
+	r.out = &out
+	if err := r.run(strings.NewReader(script), ""); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+
+func TestRepl(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "board.txt")
+	tree, err := load(file, []ranktree.Option{ranktree.WithSkipList()}, 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &repl{tree: tree, file: file}
+
+	script := `
+ZADD 10 alice 30 bob 20 "charles \"c\" darwin"
+zadd 15 alice 40 dave
+ZINCRBY 5 'bob'
+ZSCORE bob
+ZSCORE nobody
+ZRANK alice
+ZREVRANK dave
+ZCARD
+ZCOUNT (15 +inf
+ZRANGE 0 1 WITHSCORES
+ZREVRANGE 0 -1
+ZRANGEBYSCORE -inf 20
+ZPOPMIN
+ZREM dave nobody
+ZRANGE 5 10
+INFO
+SAVE
+QUIT
+`
+	want := `(integer) 3
+(integer) 1
+"35"
+"35"
+(nil)
+(integer) 0
+(integer) 0
+(integer) 4
+(integer) 3
+1) "alice"
+2) "15"
+3) "charles \"c\" darwin"
+4) "20"
+1) "dave"
+2) "bob"
+3) "charles \"c\" darwin"
+4) "alice"
+1) "alice"
+2) "charles \"c\" darwin"
+1) "alice"
+2) "15"
+(integer) 1
+(empty array)
+file: ` + file + `
+members: 2
+scores: [20, 35]
+unsaved changes: true
+OK
+`
+	if got := runScript(t, r, script); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+
+	// load the saved file
+	tree, err = load(file, nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = &repl{tree: tree, file: file}
+	if got := runScript(t, r, "ZRANGE 0 -1 WITHSCORES\n"); got != "1) \"charles \\\"c\\\" darwin\"\n2) \"20\"\n3) \"bob\"\n4) \"35\"\n" {
+		t.Errorf("loaded tree:\n%s", got)
+	}
+
+	if got := runScript(t, r, "TREE\n"); strings.HasPrefix(got, "RANKTREE [0, 1000] 2\n  NODE [0, 1000] 2\n") == false {
+		t.Errorf("TREE:\n%s", got)
+	}
+}
+
+
+func TestRepl_Errors(t *testing.T) {
+	tree, _ := ranktree.New(0, 100)
+	tree.Add("alice", 10)
+	r := &repl{tree: tree, file: filepath.Join(t.TempDir(), "board.txt")}
+
+	script := `NOSUCH
+ZCARD x
+ZADD 10
+ZADD ten bob
+ZADD 10 bob 200 charles
+ZINCRBY 100 alice
+ZCOUNT a b
+ZRANGE 0 -1 BYSCORE
+ZADD "unbalanced bob
+ZPOPMAX -1
+ZRANGE 0 -1 WITHSCORES
+`
+	got := runScript(t, r, script)
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 12 {
+		t.Fatalf("output:\n%s", got)
+	}
+	for i, line := range lines[:10] {
+		if strings.HasPrefix(line, "(error) ERR ") == false {
+			t.Errorf("line %d = %q, want an error", i + 1, line)
+		}
+	}
+
+	// failed commands change nothing
+	if lines[10] != `1) "alice"` || lines[11] != `2) "10"` || r.dirty {
+		t.Errorf("tree after errors:\n%s", got)
+	}
+}
+
+
+func TestRepl_Ties(t *testing.T) {
+	tree, _ := ranktree.New(0, 100)
+	r := &repl{tree: tree, file: filepath.Join(t.TempDir(), "board.txt")}
+
+	// ZREVRANK agrees with ZREVRANGE on equal scores
+	script := `ZADD 20 c 20 a 30 d 20 b 10 e
+ZREVRANGE 0 -1
+ZREVRANK d
+ZREVRANK a
+ZREVRANK b
+ZREVRANK c
+ZREVRANK e
+ZRANK a
+ZRANK c
+`
+	want := `(integer) 5
+1) "d"
+2) "a"
+3) "b"
+4) "c"
+5) "e"
+(integer) 0
+(integer) 1
+(integer) 2
+(integer) 3
+(integer) 4
+(integer) 1
+(integer) 3
+`
+	if got := runScript(t, r, script); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+
+func TestRepl_Quit(t *testing.T) {
+	tree, _ := ranktree.New(0, 100)
+	r := &repl{tree: tree, file: filepath.Join(t.TempDir(), "board.txt")}
+
+	// QUIT without changes
+	if got := runScript(t, r, "QUIT\nZCARD\n"); got != "" {
+		t.Errorf("output after QUIT: %q", got)
+	}
+
+	// QUIT with unsaved changes asks again, another command resets it
+	got := runScript(t, r, "ZADD 1 a\nQUIT\nZCARD\nQUIT\nEXIT\nZCARD\n")
+	if strings.Count(got, "unsaved changes") != 2 || strings.Count(got, "(integer) 1") != 2 {
+		t.Errorf("output:\n%s", got)
+	}
+
+	// SAVE to another file keeps the changes unsaved
+	other := filepath.Join(t.TempDir(), "other.txt")
+	r.dirty = false
+	got = runScript(t, r, "ZADD 2 b\nSAVE " + other + "\nQUIT\n")
+	if strings.Contains(got, "unsaved changes") == false {
+		t.Errorf("output:\n%s", got)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Error(err)
+	}
+}
+
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := load(filepath.Join(dir, "new.txt"), nil, 0, 1 << 40); err != nil {
+		t.Errorf("huge range of the default backend: %v", err)
+	}
+
+	file := filepath.Join(dir, "huge.txt")
+	os.WriteFile(file, []byte("ranktree 1 0 1099511627776 0\n"), 0644)
+	if _, err := load(file, []ranktree.Option{ranktree.WithFenwick()}, 0, 10); errors.Is(err, ranktree.ErrRangeTooLarge) == false {
+		t.Errorf("huge range of a file with a dense backend: %v", err)
+	}
+	if tree, err := load(file, []ranktree.Option{ranktree.WithSkipList()}, 0, 10); err != nil || tree.Add("a", 1 << 40) == false {
+		t.Errorf("load() = %v", err)
+	}
+
+	os.WriteFile(file, []byte("not a ranktree\n"), 0644)
+	if _, err := load(file, nil, 0, 10); err == nil || strings.HasPrefix(err.Error(), file) == false {
+		t.Errorf("load() of an invalid file = %v", err)
+	}
+}
+
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line	string
+		want	[]string
+	}{
+		{"", nil},
+		{"  ZADD  1\tbob ", []string{"ZADD", "1", "bob"}},
+		{`ZADD 1 "bob \"b\"\n" 'a b'`, []string{"ZADD", "1", "bob \"b\"\n", "a b"}},
+		{`ZADD 1 ""`, []string{"ZADD", "1", ""}},
+	}
+	for _, test := range tests {
+		if got, err := splitArgs(test.line); err != nil || reflect.DeepEqual(got, test.want) == false {
+			t.Errorf("splitArgs(%q) = %q, %v", test.line, got, err)
+		}
+	}
+
+	for _, line := range []string{`"bob`, `'bob`, `"bob"x`, `'a'b`} {
+		if _, err := splitArgs(line); err == nil {
+			t.Errorf("splitArgs(%q) succeeded", line)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package ranktree
+
+import (
+	"slices"
+	"sync/atomic"
+)
+
+
+// A RankTree shares its storage with its snapshots, see Snapshot(). Every part of the storage is tagged with
+// the generation which allocated it, and a RankTree writes in place only the parts of its own generation:
+// any other part is copied first, with the parts on the path from the root to it (path copying).
+// Snapshot() moves the RankTree to a new generation, so the snapshot keeps the old parts.
+
+
+// lastGen is the last generation given out by newGen().
+var lastGen atomic.Uint64
+
+
+// Returns a new generation, which owns no storage yet.
+func newGen() uint64 {
+	return lastGen.Add(1)
+}
+
+
+const (
+	cowBits		= 6				// index bits of a page
+	cowWidth	= 1 << cowBits	// number of entries of a page
+)
+
+
+// cowArray is an array of T stored in a tree of pages, which are copied on write.
+// A write copies at most one page per level, the other pages stay shared with the snapshots.
+// Missing pages read as zero values, so only the pages written take memory.
+type cowArray[T any] struct {
+	root	*cowPage[T]
+	size	int			// number of entries
+	shift	int			// index bits above the leaf pages, the root covers cowWidth << shift entries
+}
+
+
+// cowPage is an inner page of a cowArray, or a leaf page holding the entries.
+type cowPage[T any] struct {
+	gen		uint64			// generation which may write the page in place
+	pages	[]*cowPage[T]	// children of an inner page, nil if missing
+	values	[]T				// entries of a leaf page
+}
+
+
+// Returns an array of <size> zero values.
+func newCowArray[T any](size int) cowArray[T] {
+	a := cowArray[T]{}
+	a.resize(size, 0)
+	return a
+}
+
+
+// Returns the entry i.
+func (a *cowArray[T]) get(i int) (v T) {
+	if p := a.page(i); p != nil {
+		v = p.values[i & (cowWidth - 1)]
+	}
+	return
+}
+
+
+// Returns a pointer to the entry i, which must not be written, or nil if its page is missing.
+func (a *cowArray[T]) at(i int) *T {
+	if p := a.page(i); p != nil {
+		return &p.values[i & (cowWidth - 1)]
+	}
+	return nil
+}
+
+
+// Returns the leaf page of the entry i, nil if it is missing.
+func (a *cowArray[T]) page(i int) *cowPage[T] {
+	p := a.root
+	for shift := a.shift; p != nil && shift > 0; shift -= cowBits {
+		p = p.pages[(i >> shift) & (cowWidth - 1)]
+	}
+	return p
+}
+
+
+// Returns a pointer to the entry i to write it with <gen>,
+// the pages on the path to it are allocated or copied as needed.
+func (a *cowArray[T]) edit(i int, gen uint64) *T {
+	p := ownPage(&a.root, a.shift, gen)
+	for shift := a.shift; shift > 0; shift -= cowBits {
+		p = ownPage(&p.pages[(i >> shift) & (cowWidth - 1)], shift - cowBits, gen)
+	}
+	return &p.values[i & (cowWidth - 1)]
+}
+
+
+// Sets the entry i to <v> with <gen>.
+func (a *cowArray[T]) set(i int, v T, gen uint64) {
+	*a.edit(i, gen) = v
+}
+
+
+// Returns the page at *pp to write it with <gen>, it is allocated if missing, or replaced by a copy
+// if it belongs to another generation. <shift> is the index bits above the page, 0 for a leaf page.
+func ownPage[T any](pp **cowPage[T], shift int, gen uint64) *cowPage[T] {
+	p := *pp
+	switch {
+	case p == nil:
+		p = &cowPage[T]{gen: gen}
+		if shift == 0 {
+			p.values = make([]T, cowWidth)
+		} else {
+			p.pages = make([]*cowPage[T], cowWidth)
+		}
+	case p.gen != gen:
+		p = &cowPage[T]{gen: gen, pages: slices.Clone(p.pages), values: slices.Clone(p.values)}
+	default:
+		return p
+	}
+	*pp = p
+	return p
+}
+
+
+// Grows the array to <size> entries with <gen>, the new entries are zero values.
+// The array never shrinks, a smaller <size> is ignored.
+func (a *cowArray[T]) resize(size int, gen uint64) {
+	for cowWidth << a.shift < size {
+		// the old root becomes the first child of a new root
+		if a.root != nil {
+			root := &cowPage[T]{gen: gen, pages: make([]*cowPage[T], cowWidth)}
+			root.pages[0] = a.root
+			a.root = root
+		}
+		a.shift += cowBits
+	}
+	a.size = max(a.size, size)
+}
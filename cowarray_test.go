@@ -0,0 +1,69 @@
+package ranktree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+
+func TestCowArray(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	gen := newGen()
+	a := newCowArray[int](10)
+	want := make([]int, 10)
+
+	for _, size := range []int{10, 64, 65, 5000, 300000} {
+		a.resize(size, gen)
+		want = append(want, make([]int, size - len(want))...)
+		for i := 0; i < 1000; i++ {
+			k := r.Intn(size)
+			want[k] = r.Int()
+			a.set(k, want[k], gen)
+		}
+		*a.edit(size - 1, gen) += 1
+		want[size - 1] += 1
+
+		if a.size != size {
+			t.Errorf("size = %d, want %d", a.size, size)
+		}
+		for k, v := range want {
+			if a.get(k) != v {
+				t.Fatalf("get(%d) = %d, want %d", k, a.get(k), v)
+			}
+		}
+	}
+}
+
+
+func TestCowArray_CopyOnWrite(t *testing.T) {
+	gen := newGen()
+	a := newCowArray[int](100000)
+	for i := 0; i < a.size; i += 7 {
+		a.set(i, i, gen)
+	}
+
+	// a copy keeps its pages, a write of another generation copies only the path to the entry
+	old := a
+	gen = newGen()
+	a.set(7, -7, gen)
+	a.resize(1 << 20, gen)
+	a.set(1 << 19, 1, gen)
+
+	if old.get(7) != 7 || a.get(7) != -7 || old.get(14) != 14 || a.get(14) != 14 {
+		t.Errorf("get(7) = %d, %d, get(14) = %d, %d after a write of another generation", old.get(7), a.get(7), old.get(14), a.get(14))
+	}
+	if old.page(14) != old.page(7) || a.page(14) != a.page(7) || old.page(7) == a.page(7) {
+		t.Error("the page of entry 7 is not copied")
+	}
+	if old.page(700) != a.page(700) {
+		t.Error("the page of entry 700 is copied")
+	}
+	if old.size != 100000 || old.get(1 << 19) != 0 {
+		t.Errorf("size = %d after resize() of a copy", old.size)
+	}
+
+	// missing pages
+	if p := a.at(1 << 19 + 1 << 10); p != nil || a.get(1 << 19 + 1 << 10) != 0 {
+		t.Error("a missing page is not nil")
+	}
+}
@@ -0,0 +1,94 @@
+package ranktree
+
+import (
+	"iter"
+)
+
+
+// Diff returns an iterator over the changes from <a> to <b>, e.g. between yesterday's and today's snapshots:
+// EventAdd for members only in <b>, EventRemove for members only in <a>,
+// and EventScoreChange for members whose score is changed. Members with the same score are skipped.
+// Events are streamed in rank order from the lowest score, by the new score (or the old score if removed),
+// lexicographical is used for members with equal score. Ranks are filled in the Events.
+// It costs O((Card(a) + Card(b)) * log(range)) time and O(1) memory.
+// <a> and <b> must not be modified during the iteration, use Snapshot() for live RankTrees.
+func Diff(a, b RankTreeView) iter.Seq[Event] {
+	return func(yield func(Event) bool) {
+		ia, ib := a.Iterator(false), b.Iterator(false)
+		removed, changed := nextRemoved(ia, a, b), nextChanged(ib, a, b)
+
+		for removed != nil || changed != nil {
+			var e *Event
+			if changed == nil || (removed != nil && lessEvent(removed.OldScore, removed.Member, changed.NewScore, changed.Member)) {
+				e, removed = removed, nextRemoved(ia, a, b)
+			} else {
+				e, changed = changed, nextChanged(ib, a, b)
+			}
+
+			if !yield(*e) {
+				return
+			}
+		}
+	}
+}
+
+
+// Returns whether (score1, member1) is ordered before (score2, member2) from the lowest score.
+func lessEvent(score1 int, member1 string, score2 int, member2 string) bool {
+	return score1 < score2 || (score1 == score2 && member1 < member2)
+}
+
+
+// Moves <it> over <a> to the next member which is not in <b>.
+// Returns its EventRemove, or nil if there is none.
+func nextRemoved(it *Iterator, a, b RankTreeView) *Event {
+	for it.Next() {
+		if b.Score(it.Member()) != -1 {
+			continue
+		}
+
+		return &Event{
+			Type: EventRemove,
+			Member: it.Member(),
+			OldScore: it.Score(),
+			NewScore: -1,
+			OldRank: it.Rank(),
+			NewRank: -1,
+			OldRevRank: a.RevRank(it.Member()),
+			NewRevRank: -1,
+		}
+	}
+	return nil
+}
+
+
+// Moves <it> over <b> to the next member which is not in <a>, or whose score is changed.
+// Returns its EventAdd or EventScoreChange, or nil if there is none.
+func nextChanged(it *Iterator, a, b RankTreeView) *Event {
+	for it.Next() {
+		member := it.Member()
+		old := a.Score(member)
+		if old == it.Score() {
+			continue
+		}
+
+		e := &Event{
+			Type: EventScoreChange,
+			Member: member,
+			OldScore: old,
+			NewScore: it.Score(),
+			OldRank: -1,
+			NewRank: it.Rank(),
+			OldRevRank: -1,
+			NewRevRank: b.RevRank(member),
+		}
+		if old == -1 {
+			e.Type = EventAdd
+		} else {
+			e.OldRank = a.Rank(member)
+			e.OldRevRank = a.RevRank(member)
+		}
+		return e
+	}
+	return nil
+}
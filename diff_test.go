@@ -0,0 +1,61 @@
+package ranktree
+
+import (
+	"testing"
+)
+
+
+func TestDiff(t *testing.T) {
+	tree, err := New(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 10)
+	tree.Add("b", 20)
+	tree.Add("c", 30)
+	tree.Add("d", 40)
+	tree.Add("e", 50)
+	yesterday := tree.Snapshot()
+
+	tree.Remove("b")
+	tree.IncrementBy("c", 5)
+	tree.Add("f", 30)
+	tree.Remove("e")
+	tree.Add("g", 0)
+	tree.UpdateScore("a", 50, false)
+
+	want := []Event{
+		{EventAdd, "g", -1, 0, -1, 0, -1, 4},
+		{EventRemove, "b", 20, -1, 1, -1, 3, -1},
+		{EventAdd, "f", -1, 30, -1, 1, -1, 3},
+		{EventScoreChange, "c", 30, 35, 2, 2, 2, 2},
+		{EventScoreChange, "a", 10, 50, 0, 4, 4, 0},
+		{EventRemove, "e", 50, -1, 4, -1, 0, -1},
+	}
+
+	var events []Event
+	for e := range Diff(yesterday, tree) {
+		events = append(events, e)
+	}
+	if len(events) != len(want) {
+		t.Fatalf("len(events) = %d, want %d, %v", len(events), len(want), events)
+	}
+	for i, e := range events {
+		if e != want[i] {
+			t.Errorf("events[%d] = %v, want %v", i, e, want[i])
+		}
+	}
+
+	// stop early
+	n := 0
+	for range Diff(yesterday, tree) {
+		if n++; n == 2 {
+			break
+		}
+	}
+
+	for e := range Diff(tree, tree) {
+		t.Errorf("Diff(tree, tree) yields %v", e)
+	}
+}
@@ -0,0 +1,127 @@
+package ranktree
+
+
+// fenwickTree is a backend based on a Fenwick tree (binary indexed tree) over the counts of the positions.
+// It takes one count per position, which suits dense and small score ranges.
+type fenwickTree struct {
+	denseLeaves
+	tree	cowArray[int32]		// 1-based, tree[i] is the count of the positions [i - lowbit(i), i)
+}
+
+
+func newFenwickTree() backend {
+	return new(fenwickTree)
+}
+
+
+// WithFenwick makes the RankTree count the members with a Fenwick tree (binary indexed tree)
+// instead of the default segment tree. It takes one count per score, which is smaller and faster
+// for Rank() and Count() over dense and small score ranges.
+func WithFenwick() Option {
+	return func(tree *RankTree) {
+		tree.backend = newFenwickTree()
+	}
+}
+
+
+func (fw *fenwickTree) reset(size int) {
+	fw.resizeLeaves(size)
+	fw.tree = newCowArray[int32](size + 1)
+}
+
+
+func (fw *fenwickTree) empty() backend {
+	return newFenwickTree()
+}
+
+
+func (fw *fenwickTree) share() backend {
+	c := *fw
+	fw.gen = newGen()
+	return &c
+}
+
+
+func (fw *fenwickTree) grow(upper bool) {
+	// turn the old tree into the counts of the positions, then build the new one in O(size)
+	size := fw.tree.size - 1
+	counts := make([]int32, size * 2 + 1)
+	offset := 0
+	if upper {
+		offset = size
+	}
+	for i := 1; i <= size; i++ {
+		counts[i + offset] = fw.tree.get(i)
+	}
+	for i := size; i > 0; i-- {
+		if j := i + i & -i; j <= size {
+			counts[j + offset] -= counts[i + offset]
+		}
+	}
+
+	for i := 1; i < len(counts); i++ {
+		if j := i + i & -i; j < len(counts) {
+			counts[j] += counts[i]
+		}
+	}
+
+	fw.growLeaves(upper)
+	fw.tree = newCowArray[int32](len(counts))
+	for i, n := range counts {
+		if n != 0 {
+			fw.tree.set(i, n, fw.gen)
+		}
+	}
+}
+
+
+func (fw *fenwickTree) add(pos, delta int) {
+	for i := pos + 1; i < fw.tree.size; i += i & -i {
+		*fw.tree.edit(i, fw.gen) += int32(delta)
+	}
+}
+
+
+func (fw *fenwickTree) addAll(positions, deltas []int) {
+	for k, pos := range positions {
+		fw.add(pos, deltas[k])
+	}
+}
+
+
+func (fw *fenwickTree) prefix(pos int) (sum int) {
+	if pos >= fw.tree.size {
+		pos = fw.tree.size - 1
+	}
+
+	for i := pos; i > 0; i -= i & -i {
+		sum += int(fw.tree.get(i))
+	}
+	return
+}
+
+
+func (fw *fenwickTree) prefixAll(positions []int) []int {
+	sums := make([]int, len(positions))
+	for k, pos := range positions {
+		sums[k] = fw.prefix(pos)
+	}
+	return sums
+}
+
+
+func (fw *fenwickTree) search(rank int) (pos, offset int) {
+	// find the last position with prefix(pos) <= rank
+	step := 1
+	for step * 2 < fw.tree.size {
+		step *= 2
+	}
+
+	for ; step > 0; step /= 2 {
+		if next := pos + step; next < fw.tree.size && int(fw.tree.get(next)) <= rank {
+			pos = next
+			rank -= int(fw.tree.get(next))
+		}
+	}
+	return pos, rank
+}
@@ -0,0 +1,117 @@
+package ranktree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+
+// Version of the file format written by WriteTo().
+const fileVersion = 1
+
+
+// WriteTo writes the score range and the members of the RankTree to <w>, which can be read back by Load().
+// The format is text, a header line "ranktree <version> <low> <high> <card>",
+// followed by a line "<score> <member>" for each member from the lowest score,
+// where the member is quoted as a Go string literal.
+// Returns the number of bytes written.
+func (tree *RankTree) WriteTo(w io.Writer) (n int64, err error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	fmt.Fprintf(bw, "ranktree %d %d %d %d\n", fileVersion, tree.minScore, tree.maxScore, tree.count)
+	for member, score := range tree.All() {
+		bw.WriteString(strconv.Itoa(score))
+		bw.WriteByte(' ')
+		bw.WriteString(strconv.Quote(member))
+		bw.WriteByte('\n')
+	}
+
+	err = bw.Flush()
+	return cw.n, err
+}
+
+
+// Load creates a RankTree from <r> written by WriteTo(), <opts> are passed to New().
+// The backend is not recorded in the file: without a backend option, a range too large
+// for the default segment tree is stored in a skip list, e.g. the range of NewUnbounded(), see New().
+func Load(r io.Reader, opts ...Option) (*RankTree, error) {
+	br := bufio.NewReader(r)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	var version, low, high, card int
+	if _, err := fmt.Sscanf(line, "ranktree %d %d %d %d\n", &version, &low, &high, &card); err != nil {
+		return nil, errors.New("not a ranktree file")
+	}
+	if version != fileVersion {
+		return nil, fmt.Errorf("unsupported file version %d", version)
+	}
+
+	tree, err := New(low, high, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for n := 2; ; n++ {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		} else if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		score, member, err := parseLine(strings.TrimSuffix(line, "\n"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		if tree.Score(member) != -1 {
+			return nil, fmt.Errorf("line %d: duplicate member %q", n, member)
+		}
+		if tree.Add(member, score) == false {
+			return nil, fmt.Errorf("line %d: score %d out of the range", n, score)
+		}
+	}
+
+	if tree.Card() != card {
+		return nil, fmt.Errorf("%d members, the header says %d", tree.Card(), card)
+	}
+	return tree, nil
+}
+
+
+// Parses a line "<score> <quoted member>".
+func parseLine(line string) (score int, member string, err error) {
+	s, quoted, ok := strings.Cut(line, " ")
+	if ok == false {
+		return 0, "", errors.New("missing member")
+	}
+
+	if score, err = strconv.Atoi(s); err != nil {
+		return 0, "", errors.New("invalid score")
+	}
+	if member, err = strconv.Unquote(quoted); err != nil {
+		return 0, "", errors.New("invalid member")
+	}
+	return score, member, nil
+}
+
+
+// countWriter counts the bytes written to w.
+type countWriter struct {
+	w	io.Writer
+	n	int64
+}
+
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
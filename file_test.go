@@ -0,0 +1,115 @@
+package ranktree
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+
+func TestRankTree_WriteTo(t *testing.T) {
+	tree, err := New(5, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Add("alice", 10)
+	tree.Add("bob", 30)
+	tree.Add("charles", 10)
+	tree.Add("dave \"the\" builder\n", 1000)
+
+	var buf bytes.Buffer
+	n, err := tree.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `ranktree 1 5 1000 4
+10 "alice"
+10 "charles"
+30 "bob"
+1000 "dave \"the\" builder\n"
+`
+	if buf.String() != want || n != int64(len(want)) {
+		t.Fatalf("WriteTo() = %d, %q", n, buf.String())
+	}
+
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRankTree(t, loaded, 5, 1000, 4)
+	checkTreeCounts(t, loaded)
+	for member, score := range tree.All() {
+		if loaded.Score(member) != score {
+			t.Errorf("loaded Score(%q) = %d, want %d", member, loaded.Score(member), score)
+		}
+	}
+
+	// options are passed to New()
+	buf.Reset()
+	tree.WriteTo(&buf)
+	if loaded, err = Load(&buf, WithAutoExtend()); err != nil || loaded.Add("erin", 2000) == false {
+		t.Errorf("Load(WithAutoExtend()) = %v", err)
+	}
+
+	// the score range, not the range of the root, is written after ExtendRange()
+	tree.ExtendRange(3, 1500)
+	buf.Reset()
+	tree.WriteTo(&buf)
+	if header, _, _ := strings.Cut(buf.String(), "\n"); header != "ranktree 1 3 1500 4" {
+		t.Errorf("header after ExtendRange() = %q", header)
+	}
+	if loaded, err = Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.minScore != 3 || loaded.maxScore != 1500 || loaded.Add("erin", 1501) || loaded.Add("erin", 2) {
+		t.Errorf("loaded range = (%d, %d), want (3, 1500)", loaded.minScore, loaded.maxScore)
+	}
+	checkTreeCounts(t, loaded)
+
+	// an unbounded tree is loaded without options
+	unbounded, _ := NewUnbounded()
+	unbounded.Add("alice", math.MaxInt)
+	buf.Reset()
+	unbounded.WriteTo(&buf)
+	if loaded, err = Load(&buf); err != nil || loaded.Score("alice") != math.MaxInt {
+		t.Errorf("Load(unbounded) = %v", err)
+	}
+
+	// empty tree
+	empty, _ := New(0, 0)
+	buf.Reset()
+	empty.WriteTo(&buf)
+	if loaded, err = Load(&buf); err != nil || loaded.Card() != 0 {
+		t.Errorf("Load(empty) = %v", err)
+	}
+}
+
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []string{
+		"",
+		"hello\n",
+		"ranktree 2 0 10 0\n",
+		"ranktree 1 10 0 0\n",
+		"ranktree 1 0 10 1\n5 \"a\"\n5 \"b\"\n",
+		"ranktree 1 0 10 2\n5 \"a\"\n",
+		"ranktree 1 0 10 1\n5 a\n",
+		"ranktree 1 0 10 1\nfive \"a\"\n",
+		"ranktree 1 0 10 1\n5\n",
+		"ranktree 1 0 10 1\n11 \"a\"\n",
+		"ranktree 1 0 10 2\n5 \"a\"\n6 \"a\"\n",
+	}
+
+	for _, s := range tests {
+		if _, err := Load(strings.NewReader(s)); err == nil {
+			t.Errorf("Load(%q) succeeded", s)
+		}
+	}
+
+	// the trailing newline is optional
+	if tree, err := Load(strings.NewReader("ranktree 1 0 10 1\n5 \"a\"")); err != nil || tree.Score("a") != 5 {
+		t.Errorf("Load() without the trailing newline = %v", err)
+	}
+}
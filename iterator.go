@@ -0,0 +1,167 @@
+package ranktree
+
+import (
+	"iter"
+)
+
+
+// Iterator walks the members of a RankTree in rank order without allocating.
+// The Iterator is invalidated by any modification of the RankTree.
+//
+//	it := tree.Iterator(true)
+//	for it.Next() {
+//		fmt.Println(it.Rank(), it.Member(), it.Score())
+//	}
+type Iterator struct {
+	tree	*RankTree
+	node	*TreeNode	// current leaf node, nil if exhausted
+	index	int			// index of member in node.members
+	rank	int			// rank of the current member in the iteration order
+	reverse	bool		// from the highest to the lowest score
+	seeked	bool		// positioned at a member not yet returned by Next()
+}
+
+
+// Iterator returns an Iterator positioned before the first member.
+// If reverse is false, members are ordered from the lowest to the highest score.
+// Otherwise, members are ordered from the highest to the lowest score.
+// Lexicographical is used for members with equal score.
+func (tree *RankTree) Iterator(reverse bool) *Iterator {
+	it := tree.newIterator(reverse)
+	it.SeekRank(0)
+	return it
+}
+
+
+// Returns an Iterator which is not positioned yet.
+func (tree *RankTree) newIterator(reverse bool) *Iterator {
+	return &Iterator{tree: tree, reverse: reverse}
+}
+
+
+// SeekRank positions the Iterator before the member with <rank> in the iteration order,
+// so that the next call of Next() moves to it.
+// Returns false if <rank> is out of the range.
+func (it *Iterator) SeekRank(rank int) bool {
+	if rank < 0 || rank >= it.tree.count {
+		it.node = nil
+		it.seeked = true
+		return false
+	}
+
+	if it.reverse {
+		it.node, it.index = it.tree.findFromRight(rank, true)
+	} else {
+		it.node, it.index = it.tree.findFromRight(it.tree.count - rank - 1, false)
+	}
+	it.rank = rank
+	it.seeked = true
+	return true
+}
+
+
+// SeekScore positions the Iterator before the first member with a score
+// not less than (ascending) or not greater than (descending) <score>,
+// so that the next call of Next() moves to it.
+// Returns false if there is no such member.
+func (it *Iterator) SeekScore(score int) bool {
+	tree := it.tree
+	if it.reverse {
+		return it.SeekRank(tree.countRightArea(score))
+	}
+	return it.SeekRank(tree.countLeftArea(score))
+}
+
+
+// Next moves the Iterator to the next member.
+// Returns false if there are no more members.
+func (it *Iterator) Next() bool {
+	if it.seeked {
+		it.seeked = false
+		return it.node != nil
+	}
+
+	if it.node == nil {
+		return false
+	}
+
+	it.rank++
+	if it.index < len(it.node.members) - 1 {
+		it.index++
+		return true
+	}
+
+	// move to the next node in the linked list
+	it.node, it.index = it.tree.neighborNode(it.node, it.reverse), 0
+	return it.node != nil
+}
+
+
+// Member returns the current member.
+func (it *Iterator) Member() string {
+	return it.node.members[it.index]
+}
+
+
+// Score returns the score of the current member.
+func (it *Iterator) Score() int {
+	return it.node.score
+}
+
+
+// Rank returns the 0-based rank of the current member in the iteration order,
+// i.e. the index in Range() for ascending Iterators, or the index in RevRange() for descending Iterators.
+func (it *Iterator) Rank() int {
+	return it.rank
+}
+
+
+// Returns rank and score of the current member.
+func (it *Iterator) RankWithScore() RankWithScore {
+	return RankWithScore{
+		Member: it.node.members[it.index],
+		Score: it.node.score }
+}
+
+
+// All returns an iterator over members and scores, ordered from the lowest to the highest score.
+func (tree *RankTree) All() iter.Seq2[string, int] {
+	return func(yield func(string, int) bool) {
+		it := tree.Iterator(false)
+		for it.Next() {
+			if !yield(it.Member(), it.Score()) {
+				return
+			}
+		}
+	}
+}
+
+
+// Backward returns an iterator over members and scores, ordered from the highest to the lowest score.
+func (tree *RankTree) Backward() iter.Seq2[string, int] {
+	return func(yield func(string, int) bool) {
+		it := tree.Iterator(true)
+		for it.Next() {
+			if !yield(it.Member(), it.Score()) {
+				return
+			}
+		}
+	}
+}
+
+
+// ScoreRange returns an iterator over members and scores with a score between min and max,
+// ordered from the lowest to the highest score.
+func (tree *RankTree) ScoreRange(min, max int) iter.Seq2[string, int] {
+	return func(yield func(string, int) bool) {
+		it := tree.Iterator(false)
+		if !it.SeekScore(min) {
+			return
+		}
+		for it.Next() && it.Score() <= max {
+			if !yield(it.Member(), it.Score()) {
+				return
+			}
+		}
+	}
+}
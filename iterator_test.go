@@ -0,0 +1,130 @@
+package ranktree
+
+import (
+	"testing"
+)
+
+
+func newIteratorTestTree(t *testing.T) *RankTree {
+	tree, err := New(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 1)
+	tree.Add("b", 2)
+	tree.Add("b2", 2)
+	tree.Add("c", 4)
+	tree.Add("d", 5)
+	tree.Add("e", 6)
+	tree.Add("e2", 6)
+	tree.Add("f", 8)
+	return tree
+}
+
+
+func TestIterator(t *testing.T) {
+	tree := newIteratorTestTree(t)
+
+	var members []string
+	it := tree.Iterator(false)
+	for i := 0; it.Next(); i++ {
+		if n := it.Rank(); n != i {
+			t.Errorf("it.Rank() = %d, want %d", n, i)
+		}
+		members = append(members, it.Member())
+	}
+	checkRank(t, members, tree.Range(0, -1))
+
+	members = members[:0]
+	it = tree.Iterator(true)
+	for i := 0; it.Next(); i++ {
+		if n := it.Rank(); n != i {
+			t.Errorf("it.Rank() = %d, want %d", n, i)
+		}
+		members = append(members, it.Member())
+	}
+	checkRank(t, members, tree.RevRange(0, -1))
+
+	if it.Next() {
+		t.Error("it.Next() = true after the last member, want false")
+	}
+
+	// empty tree
+	tree, _ = New(1, 8)
+	if tree.Iterator(false).Next() {
+		t.Error("it.Next() = true on empty tree, want false")
+	}
+}
+
+
+func TestIterator_Seek(t *testing.T) {
+	tree := newIteratorTestTree(t)
+
+	it := tree.Iterator(false)
+	if !it.SeekRank(3) || !it.Next() || it.Member() != "c" || it.Rank() != 3 {
+		t.Errorf("SeekRank(3) = %s (%d), want c (3)", it.Member(), it.Rank())
+	}
+
+	if it.SeekRank(8) {
+		t.Error("SeekRank(8) = true, want false")
+	}
+
+	if !it.SeekScore(3) || !it.Next() || it.Member() != "c" || it.Score() != 4 {
+		t.Errorf("SeekScore(3) = %s (%d), want c (4)", it.Member(), it.Score())
+	}
+
+	if it.SeekScore(9) {
+		t.Error("SeekScore(9) = true, want false")
+	}
+
+	it = tree.Iterator(true)
+	if !it.SeekRank(1) || !it.Next() || it.Member() != "e" || it.Rank() != 1 {
+		t.Errorf("SeekRank(1) = %s (%d), want e (1)", it.Member(), it.Rank())
+	}
+
+	if !it.SeekScore(7) || !it.Next() || it.Member() != "e" || it.Rank() != 1 {
+		t.Errorf("SeekScore(7) = %s (%d), want e (1)", it.Member(), it.Rank())
+	}
+
+	if !it.SeekScore(3) || !it.Next() || it.Member() != "b" || it.Rank() != 5 {
+		t.Errorf("SeekScore(3) = %s (%d), want b (5)", it.Member(), it.Rank())
+	}
+}
+
+
+func TestRankTree_All(t *testing.T) {
+	tree := newIteratorTestTree(t)
+
+	var ranks []RankWithScore
+	for member, score := range tree.All() {
+		ranks = append(ranks, RankWithScore{member, score})
+	}
+	checkRankWithScore(t, ranks, []string{"a", "b", "b2", "c", "d", "e", "e2", "f"}, []int{1, 2, 2, 4, 5, 6, 6, 8})
+
+	ranks = ranks[:0]
+	for member, score := range tree.Backward() {
+		if len(ranks) == 3 {
+			break
+		}
+		ranks = append(ranks, RankWithScore{member, score})
+	}
+	checkRankWithScore(t, ranks, []string{"f", "e", "e2"}, []int{8, 6, 6})
+}
+
+
+func TestRankTree_ScoreRange(t *testing.T) {
+	tree := newIteratorTestTree(t)
+
+	var ranks []RankWithScore
+	for member, score := range tree.ScoreRange(3, 7) {
+		ranks = append(ranks, RankWithScore{member, score})
+	}
+	checkRankWithScore(t, ranks, []string{"c", "d", "e", "e2"}, []int{4, 5, 6, 6})
+
+	ranks = ranks[:0]
+	for member, score := range tree.ScoreRange(9, 12) {
+		ranks = append(ranks, RankWithScore{member, score})
+	}
+	checkRankWithScore(t, ranks, []string{}, []int{})
+}
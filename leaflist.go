@@ -0,0 +1,87 @@
+package ranktree
+
+
+// leafList is the doubly linked list of the non-empty leaf nodes of a RankTree, from the highest score.
+// The elements are stored in a cowArray and linked by index, so that they are copied on write
+// with the rest of the RankTree. Element 0 is the root of the ring: its next is the head, its prev the back.
+type leafList struct {
+	elements	cowArray[leafElement]
+	free		[]int		// indexes of removed elements
+	len			int			// number of leaf nodes
+}
+
+
+type leafElement struct {
+	prev	int
+	next	int
+	node	*TreeNode
+}
+
+
+// Returns the leaf node with the highest score, nil if the list is empty.
+func (l *leafList) head() *TreeNode {
+	return l.elements.get(l.elements.get(0).next).node
+}
+
+
+// Returns the leaf node with the lowest score, nil if the list is empty.
+func (l *leafList) back() *TreeNode {
+	return l.elements.get(l.elements.get(0).prev).node
+}
+
+
+// Returns the leaf node after <node>, i.e. with the next lower score, nil if there is none.
+func (l *leafList) next(node *TreeNode) *TreeNode {
+	return l.elements.get(l.elements.get(node.element).next).node
+}
+
+
+// Returns the leaf node before <node>, i.e. with the next higher score, nil if there is none.
+func (l *leafList) prev(node *TreeNode) *TreeNode {
+	return l.elements.get(l.elements.get(node.element).prev).node
+}
+
+
+// Inserts <node> after <mark>, or at the front if <mark> is nil, with <gen>.
+// <node> must be writable, its element is set.
+func (l *leafList) insertAfter(node, mark *TreeNode, gen uint64) {
+	at := 0
+	if mark != nil {
+		at = mark.element
+	}
+
+	var i int
+	if n := len(l.free); n > 0 {
+		i, l.free = l.free[n - 1], l.free[:n - 1]
+	} else {
+		i = max(l.elements.size, 1)
+		l.elements.resize(i + 1, gen)
+	}
+
+	next := l.elements.get(at).next
+	l.elements.set(i, leafElement{at, next, node}, gen)
+	l.elements.edit(at, gen).next = i
+	l.elements.edit(next, gen).prev = i
+	node.element = i
+	l.len++
+}
+
+
+// Removes <node> with <gen>.
+// <node> must be writable, its element is cleared.
+func (l *leafList) remove(node *TreeNode, gen uint64) {
+	e := l.elements.get(node.element)
+	l.elements.edit(e.prev, gen).next = e.next
+	l.elements.edit(e.next, gen).prev = e.prev
+	l.elements.set(node.element, leafElement{}, gen)
+
+	l.free = append(l.free, node.element)
+	node.element = 0
+	l.len--
+}
+
+
+// Replaces the leaf node of the element of <node> with <node>, e.g. a copy of it, with <gen>.
+func (l *leafList) replace(node *TreeNode, gen uint64) {
+	l.elements.edit(node.element, gen).node = node
+}
@@ -0,0 +1,70 @@
+package ranktree
+
+import (
+	"slices"
+	"testing"
+)
+
+
+// Returns the scores of <l> from the head, and checks the links backward.
+func leafListScores(t *testing.T, l *leafList) (scores []int) {
+	t.Helper()
+
+	var prev *TreeNode
+	for node := l.head(); node != nil; node = l.next(node) {
+		if l.prev(node) != prev {
+			t.Errorf("prev of %d = %v, want %v", node.score, l.prev(node), prev)
+		}
+		scores = append(scores, node.score)
+		prev = node
+	}
+	if l.back() != prev || len(scores) != l.len {
+		t.Errorf("back() = %v, len = %d, want %v, %d", l.back(), l.len, prev, len(scores))
+	}
+	return
+}
+
+
+func TestLeafList(t *testing.T) {
+	gen := newGen()
+	var l leafList
+	nodes := make([]*TreeNode, 5)
+	for i := range nodes {
+		nodes[i] = &TreeNode{score: i}
+	}
+
+	l.insertAfter(nodes[1], nil, gen)
+	l.insertAfter(nodes[3], nil, gen)
+	l.insertAfter(nodes[2], nodes[3], gen)
+	l.insertAfter(nodes[0], nodes[1], gen)
+	if scores := leafListScores(t, &l); slices.Equal(scores, []int{3, 2, 1, 0}) == false {
+		t.Errorf("scores = %v, want [3 2 1 0]", scores)
+	}
+
+	// a copy is not changed by the writes of a new generation, which copy the nodes like RankTree.own()
+	old, oldNodes := l, slices.Clone(nodes)
+	gen = newGen()
+	for _, i := range []int{0, 2, 3} {
+		c := *nodes[i]
+		nodes[i] = &c
+		l.replace(nodes[i], gen)
+	}
+	l.remove(nodes[2], gen)
+	l.remove(nodes[0], gen)
+	l.insertAfter(nodes[4], nil, gen)
+
+	if scores := leafListScores(t, &l); slices.Equal(scores, []int{4, 3, 1}) == false {
+		t.Errorf("scores = %v, want [4 3 1]", scores)
+	}
+	if scores := leafListScores(t, &old); slices.Equal(scores, []int{3, 2, 1, 0}) == false {
+		t.Errorf("scores of the copy = %v, want [3 2 1 0]", scores)
+	}
+	if l.next(nodes[4]) != nodes[3] || old.head() != oldNodes[3] {
+		t.Error("replace() changed the copy")
+	}
+
+	// removed elements are reused
+	if l.elements.size != 5 || nodes[2].element != 0 || oldNodes[2].element == 0 {
+		t.Errorf("%d elements, element of a removed node = %d", l.elements.size, nodes[2].element)
+	}
+}
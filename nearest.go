@@ -0,0 +1,77 @@
+package ranktree
+
+
+// Nearest returns up to <n> members whose scores are the closest to <score>, except <exclude>.
+// Members are ordered by the distance to <score>, ties go to the higher score,
+// and lexicographical is used for members with equal score.
+func (tree *RankTree) Nearest(score, n int, exclude ...string) []RankWithScore {
+	return tree.NearestWithin(score, n, -1, exclude...)
+}
+
+
+// NearestWithin is like Nearest(), but only returns members whose scores differ from <score>
+// by at most <maxDistance>. A negative <maxDistance> means no limit.
+func (tree *RankTree) NearestWithin(score, n, maxDistance int, exclude ...string) []RankWithScore {
+	ranks := make([]RankWithScore, 0)
+	if n <= 0 || tree.count == 0 {
+		return ranks
+	}
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, v := range exclude {
+		excluded[v] = true
+	}
+
+	// clamp <score> to the range to find the start node
+	start := score
+	if start < tree.minScore {
+		start = tree.minScore
+	} else if start > tree.maxScore {
+		start = tree.maxScore
+	}
+
+	// <hi> is the first non-empty node with a score >= start, <lo> is the first one with a score < start
+	var hi, lo *TreeNode
+	if node := tree.find(start); node != nil && len(node.members) > 0 {
+		hi = node
+		lo = tree.neighborNode(node, true)
+	} else {
+		hi = tree.findNextGreaterNode(start)
+		lo = tree.findNextSmallerNode(start)
+	}
+
+	// expand outward, from the closer node
+	for len(ranks) < n && (hi != nil || lo != nil) {
+		var next *TreeNode
+		if lo == nil || (hi != nil && distance(hi.score, score) <= distance(lo.score, score)) {
+			next = hi
+			hi = tree.neighborNode(hi, false)
+		} else {
+			next = lo
+			lo = tree.neighborNode(lo, true)
+		}
+
+		if maxDistance >= 0 && distance(next.score, score) > uint64(maxDistance) {
+			break
+		}
+
+		for _, member := range next.members {
+			if len(ranks) == n {
+				break
+			}
+			if excluded[member] == false {
+				ranks = append(ranks, RankWithScore{member, next.score})
+			}
+		}
+	}
+	return ranks
+}
+
+
+// Returns the distance between <a> and <b>, unsigned so that it does not overflow for any ints.
+func distance(a, b int) uint64 {
+	if a < b {
+		return uint64(b) - uint64(a)
+	}
+	return uint64(a) - uint64(b)
+}
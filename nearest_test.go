@@ -0,0 +1,55 @@
+package ranktree
+
+import (
+	"math"
+	"testing"
+)
+
+
+func TestRankTree_Nearest(t *testing.T) {
+	tree, err := New(0, 3000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 1000)
+	tree.Add("b", 1400)
+	tree.Add("c", 1500)
+	tree.Add("c2", 1500)
+	tree.Add("d", 1550)
+	tree.Add("e", 1450)
+	tree.Add("f", 2000)
+
+	checkRankWithScore(t, tree.Nearest(1500, 3), []string{"c", "c2", "d"}, []int{1500, 1500, 1550})
+	checkRankWithScore(t, tree.Nearest(1500, 4, "c"), []string{"c2", "d", "e", "b"}, []int{1500, 1550, 1450, 1400})
+	checkRankWithScore(t, tree.Nearest(1700, 2), []string{"d", "c"}, []int{1550, 1500})
+	checkRankWithScore(t, tree.Nearest(1200, 2), []string{"b", "a"}, []int{1400, 1000})
+	checkRankWithScore(t, tree.Nearest(5000, 2), []string{"f", "d"}, []int{2000, 1550})
+	checkRankWithScore(t, tree.Nearest(0, 1), []string{"a"}, []int{1000})
+	checkRankWithScore(t, tree.Nearest(1500, 10), []string{"c", "c2", "d", "e", "b", "f", "a"}, []int{1500, 1500, 1550, 1450, 1400, 2000, 1000})
+	checkRankWithScore(t, tree.Nearest(1500, 0), []string{}, []int{})
+
+	checkRankWithScore(t, tree.NearestWithin(1500, 10, 60, "c2"), []string{"c", "d", "e"}, []int{1500, 1550, 1450})
+	checkRankWithScore(t, tree.NearestWithin(1700, 10, 100), []string{}, []int{})
+}
+
+
+func TestRankTree_NearestBoundaries(t *testing.T) {
+	tree, err := NewUnbounded()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 0)
+	tree.Add("b", 1)
+	tree.Add("y", math.MaxInt - 1)
+	tree.Add("z", math.MaxInt)
+
+	// distances do not overflow at the ends of int
+	checkRankWithScore(t, tree.Nearest(math.MinInt, 4), []string{"a", "b", "y", "z"}, []int{0, 1, math.MaxInt - 1, math.MaxInt})
+	checkRankWithScore(t, tree.Nearest(math.MaxInt, 3), []string{"z", "y", "b"}, []int{math.MaxInt, math.MaxInt - 1, 1})
+	checkRankWithScore(t, tree.Nearest(1 << 62, 4), []string{"y", "z", "b", "a"}, []int{math.MaxInt - 1, math.MaxInt, 1, 0})
+	checkRankWithScore(t, tree.NearestWithin(math.MinInt, 10, math.MaxInt), []string{}, []int{})
+	checkRankWithScore(t, tree.NearestWithin(0, 10, math.MaxInt), []string{"a", "b", "y", "z"}, []int{0, 1, math.MaxInt - 1, math.MaxInt})
+	checkRankWithScore(t, tree.NearestWithin(math.MaxInt, 10, 1), []string{"z", "y"}, []int{math.MaxInt, math.MaxInt - 1})
+}
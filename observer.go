@@ -0,0 +1,213 @@
+package ranktree
+
+
+// EventType represents the kind of change of a member.
+type EventType int
+
+const (
+	EventAdd			EventType = iota	// member added
+	EventRemove								// member removed
+	EventScoreChange						// score of an existing member changed
+)
+
+
+// Event describes a change of a member in the RankTree.
+// Scores of an absent member are -1.
+// Ranks are 0-based (see Rank() and RevRank()), they are -1 if the member is absent,
+// or if the observer is registered by OnChange() instead of OnChangeWithRank().
+type Event struct {
+	Type		EventType
+	Member		string
+	OldScore	int
+	NewScore	int
+	OldRank		int
+	NewRank		int
+	OldRevRank	int
+	NewRevRank	int
+}
+
+
+// BoundaryEvent describes a change of the top-K members (ordered from high to low score).
+// Entered is the member which entered the top K, Left is the member which left the top K.
+// Either of them may be empty, e.g. when the RankTree has less than K members.
+type BoundaryEvent struct {
+	K		int
+	Entered	string
+	Left	string
+}
+
+
+// Change observer.
+type observer struct {
+	fn			func(Event)
+	withRank	bool
+}
+
+
+// Top-K watcher.
+type boundaryWatcher struct {
+	k	int
+	fn	func(BoundaryEvent)
+}
+
+
+// State of a member before or after a change.
+type memberState struct {
+	score	int
+	rank	int
+	revRank	int
+}
+
+
+// OnChange registers <fn> to be called after a member is added, removed or its score is changed.
+// Ranks in the Event are not computed, use OnChangeWithRank() if they are needed.
+// <fn> must not modify the RankTree.
+// Returns a function which unregisters <fn>.
+func (tree *RankTree) OnChange(fn func(Event)) (cancel func()) {
+	return tree.addObserver(&observer{fn: fn})
+}
+
+
+// OnChangeWithRank is like OnChange(), but the old and new ranks are filled in the Event.
+// It costs O(log(range)) for each change.
+func (tree *RankTree) OnChangeWithRank(fn func(Event)) (cancel func()) {
+	return tree.addObserver(&observer{fn: fn, withRank: true})
+}
+
+
+// WatchRankBoundary registers <fn> to be called only when the membership of the top <k> members changes,
+// i.e. a member enters or leaves the top <k> ordered from the highest to the lowest score.
+// <fn> must not modify the RankTree.
+// Returns a function which unregisters <fn>.
+func (tree *RankTree) WatchRankBoundary(k int, fn func(BoundaryEvent)) (cancel func()) {
+	w := &boundaryWatcher{k: k, fn: fn}
+	tree.watchers = append(tree.watchers, w)
+
+	return func() {
+		for i, v := range tree.watchers {
+			if v == w {
+				tree.watchers = append(tree.watchers[:i], tree.watchers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+
+// Registers a change observer.
+func (tree *RankTree) addObserver(o *observer) (cancel func()) {
+	tree.observers = append(tree.observers, o)
+
+	return func() {
+		for i, v := range tree.observers {
+			if v == o {
+				tree.observers = append(tree.observers[:i], tree.observers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+
+// Returns whether the ranks of a changed member are required by observers or watchers.
+func (tree *RankTree) needRank() bool {
+	if len(tree.watchers) > 0 {
+		return true
+	}
+	for _, o := range tree.observers {
+		if o.withRank {
+			return true
+		}
+	}
+	return false
+}
+
+
+// Returns the current state of <member>.
+// Ranks are computed only if needed.
+func (tree *RankTree) memberState(member string) memberState {
+	state := memberState{-1, -1, -1}
+	if len(tree.observers) == 0 && len(tree.watchers) == 0 {
+		return state
+	}
+
+	state.score = tree.Score(member)
+	if state.score >= 0 && tree.needRank() {
+		state.rank = tree.Rank(member)
+		state.revRank = tree.RevRank(member)
+	}
+	return state
+}
+
+
+// Notifies observers and watchers that <member> changed from <old> state.
+func (tree *RankTree) changed(member string, old memberState) {
+	if len(tree.observers) == 0 && len(tree.watchers) == 0 {
+		return
+	}
+
+	cur := tree.memberState(member)
+
+	var typ EventType
+	switch {
+	case old.score < 0 && cur.score < 0:
+		return
+	case old.score < 0:
+		typ = EventAdd
+	case cur.score < 0:
+		typ = EventRemove
+	case old.score != cur.score:
+		typ = EventScoreChange
+	default:
+		return
+	}
+
+	for _, o := range tree.observers {
+		e := Event{
+			Type: typ,
+			Member: member,
+			OldScore: old.score,
+			NewScore: cur.score,
+			OldRank: -1,
+			NewRank: -1,
+			OldRevRank: -1,
+			NewRevRank: -1,
+		}
+		if o.withRank {
+			e.OldRank, e.NewRank = old.rank, cur.rank
+			e.OldRevRank, e.NewRevRank = old.revRank, cur.revRank
+		}
+		o.fn(e)
+	}
+
+	for _, w := range tree.watchers {
+		w.check(tree, member, old.revRank, cur.revRank)
+	}
+}
+
+
+// Checks whether the top-K membership changed, <oldRevRank> and <newRevRank> are the
+// reverse ranks of the changed member (-1 if absent).
+func (w *boundaryWatcher) check(tree *RankTree, member string, oldRevRank, newRevRank int) {
+	wasIn := oldRevRank >= 0 && oldRevRank < w.k
+	isIn := newRevRank >= 0 && newRevRank < w.k
+	if wasIn == isIn {
+		return
+	}
+
+	e := BoundaryEvent{K: w.k}
+	if isIn {
+		// member pushed the k-th member out
+		e.Entered = member
+		if node, index := tree.findFromRight(w.k, false); node != nil {
+			e.Left = node.members[index]
+		}
+	} else {
+		// the k-th member moved in
+		e.Left = member
+		if node, index := tree.findFromRight(w.k - 1, false); node != nil {
+			e.Entered = node.members[index]
+		}
+	}
+	w.fn(e)
+}
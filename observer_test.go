@@ -0,0 +1,116 @@
+package ranktree
+
+import (
+	"testing"
+)
+
+
+func TestRankTree_OnChange(t *testing.T) {
+	tree, err := New(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []Event
+	cancel := tree.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+
+	tree.Add("a", 1)
+	tree.Add("a", 2) // exists, no event
+	tree.Add("b", 9) // out of range, no event
+	tree.IncrementBy("a", 2)
+	tree.UpdateScore("a", 3, false) // same score, no event
+	tree.Remove("a", "c")
+
+	want := []Event{
+		{EventAdd, "a", -1, 1, -1, -1, -1, -1},
+		{EventScoreChange, "a", 1, 3, -1, -1, -1, -1},
+		{EventRemove, "a", 3, -1, -1, -1, -1, -1},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("len(events) = %d, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e != want[i] {
+			t.Errorf("events[%d] = %+v, want %+v", i, e, want[i])
+		}
+	}
+
+	cancel()
+	tree.Add("d", 4)
+	if len(events) != len(want) {
+		t.Errorf("len(events) = %d after cancel, want %d", len(events), len(want))
+	}
+}
+
+
+func TestRankTree_OnChangeWithRank(t *testing.T) {
+	tree, err := New(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 1)
+	tree.Add("b", 2)
+	tree.Add("c", 3)
+
+	var events []Event
+	tree.OnChangeWithRank(func(e Event) {
+		events = append(events, e)
+	})
+
+	tree.IncrementBy("a", 4) // b c a
+	tree.PopMin()           // c a
+
+	want := []Event{
+		{EventScoreChange, "a", 1, 5, 0, 2, 2, 0},
+		{EventRemove, "b", 2, -1, 0, -1, 2, -1},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("len(events) = %d, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e != want[i] {
+			t.Errorf("events[%d] = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
+
+
+func TestRankTree_WatchRankBoundary(t *testing.T) {
+	tree, err := New(1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []BoundaryEvent
+	tree.WatchRankBoundary(2, func(e BoundaryEvent) {
+		events = append(events, e)
+	})
+
+	tree.Add("a", 10)       // a
+	tree.Add("b", 20)       // b a
+	tree.Add("c", 5)        // b a | c
+	tree.Add("d", 30)       // d b | a c
+	tree.IncrementBy("d", 1) // no change
+	tree.IncrementBy("c", 50) // c d | b a
+	tree.Remove("d")        // c b | a
+	tree.Remove("a")        // no change
+
+	want := []BoundaryEvent{
+		{2, "a", ""},
+		{2, "b", ""},
+		{2, "d", "a"},
+		{2, "c", "b"},
+		{2, "b", "d"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("len(events) = %d, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e != want[i] {
+			t.Errorf("events[%d] = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
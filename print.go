@@ -0,0 +1,62 @@
+package ranktree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+
+// Print writes the structure of the RankTree to <w>, for inspection.
+// With the default segment tree backend, nodes are written in pre-order, indented by their depth,
+// an internal node as "NODE [low, high] count", a leaf node as "LEAF score count [members]".
+// Subtrees without members are omitted.
+// With the other backends, the non-empty leaf nodes are written from the lowest score,
+// with the height of each node of the skip list.
+func (tree *RankTree) Print(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "RANKTREE [%d, %d] %d\n", tree.low, tree.high, tree.count)
+
+	switch b := tree.backend.(type) {
+	case *segmentTree:
+		tree.printNode(bw, b, 0, tree.low, tree.high, 1)
+	case *skipList:
+		for x := b.node(0).levels[0].forward; x != 0; x = b.node(x).levels[0].forward {
+			n := b.node(x)
+			printLeaf(bw, n.leaf, 1)
+			fmt.Fprintf(bw, " height %d\n", len(n.levels))
+		}
+	default:
+		for node := tree.list.back(); node != nil; node = tree.list.prev(node) {
+			printLeaf(bw, node, 1)
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+
+// Prints the subtree of the segment tree node <i> covering [low, high], at <depth>.
+func (tree *RankTree) printNode(w *bufio.Writer, seg *segmentTree, i, low, high, depth int) {
+	if seg.counts.get(i) == 0 {
+		return
+	}
+
+	if low == high {
+		printLeaf(w, seg.leaf(low - tree.low), depth)
+		w.WriteByte('\n')
+		return
+	}
+
+	fmt.Fprintf(w, "%sNODE [%d, %d] %d\n", strings.Repeat("  ", depth), low, high, seg.counts.get(i))
+	mid := low + (high - low) / 2
+	tree.printNode(w, seg, 2 * i + 1, low, mid, depth + 1)
+	tree.printNode(w, seg, 2 * i + 2, mid + 1, high, depth + 1)
+}
+
+
+// Prints a leaf node without a newline.
+func printLeaf(w *bufio.Writer, node *TreeNode, depth int) {
+	fmt.Fprintf(w, "%sLEAF %d %d %q", strings.Repeat("  ", depth), node.score, len(node.members), node.members)
+}
@@ -0,0 +1,56 @@
+package ranktree
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+
+func TestRankTree_Print(t *testing.T) {
+	withSegmentTree := func(tree *RankTree) {
+		tree.backend = newSegmentTree()
+	}
+
+	tests := []struct {
+		name	string
+		opt		Option
+		want	string
+	}{
+		{"segment", withSegmentTree, `RANKTREE \[0, 7\] 3
+  NODE \[0, 7\] 3
+    NODE \[0, 3\] 2
+      NODE \[0, 1\] 2
+        LEAF 1 2 \["a" "b"\]
+    NODE \[4, 7\] 1
+      NODE \[6, 7\] 1
+        LEAF 6 1 \["c d"\]
+$`},
+		{"fenwick", WithFenwick(), `RANKTREE \[0, 7\] 3
+  LEAF 1 2 \["a" "b"\]
+  LEAF 6 1 \["c d"\]
+$`},
+		{"skiplist", WithSkipList(), `RANKTREE \[0, 7\] 3
+  LEAF 1 2 \["a" "b"\] height \d+
+  LEAF 6 1 \["c d"\] height \d+
+$`},
+	}
+
+	for _, test := range tests {
+		tree, err := New(0, 7, test.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.Add("b", 1)
+		tree.Add("a", 1)
+		tree.Add("c d", 6)
+
+		var b strings.Builder
+		if err := tree.Print(&b); err != nil {
+			t.Fatal(err)
+		}
+		if regexp.MustCompile("^" + test.want).MatchString(b.String()) == false {
+			t.Errorf("%s: Print() =\n%s", test.name, b.String())
+		}
+	}
+}
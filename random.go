@@ -0,0 +1,104 @@
+package ranktree
+
+import (
+	"math/rand"
+)
+
+
+// SetRandSource sets the source of randomness of RandomMember(), RandomInScoreRange().
+// If <src> is nil, the default source of math/rand is used.
+func (tree *RankTree) SetRandSource(src rand.Source) {
+	if src == nil {
+		tree.rand = nil
+	} else {
+		tree.rand = rand.New(src)
+	}
+}
+
+
+// RandomMember returns random members of the RankTree, like Redis ZRANDMEMBER.
+// If <count> is positive, up to <count> distinct members are returned.
+// If <count> is negative, -<count> members are returned, and the same member may be returned multiple times.
+// Scores are returned only if <withScores> is true, scores[i] is the score of members[i].
+// Each member is selected uniformly by rank in O(log(range)).
+func (tree *RankTree) RandomMember(count int, withScores bool) (members []string, scores []int) {
+	var ranks []int
+	if count >= 0 {
+		ranks = tree.sampleRanks(tree.count, count)
+	} else if tree.count > 0 {
+		ranks = make([]int, -count)
+		for i := range ranks {
+			ranks[i] = tree.intn(tree.count)
+		}
+	}
+
+	members = make([]string, len(ranks))
+	if withScores {
+		scores = make([]int, len(ranks))
+	}
+	for i, rank := range ranks {
+		node, index := tree.findFromRight(tree.count - rank - 1, false)
+		members[i] = node.members[index]
+		if withScores {
+			scores[i] = node.score
+		}
+	}
+	return
+}
+
+
+// RandomInScoreRange returns up to <n> distinct random members with a score between min and max.
+// Each member is selected uniformly by rank in O(log(range)).
+func (tree *RankTree) RandomInScoreRange(min, max, n int) (ranks []RankWithScore) {
+	// ranks of members between min and max are [first, first + size)
+	first := tree.countLeftArea(min)
+	size := tree.Count(min, max)
+
+	samples := tree.sampleRanks(size, n)
+	ranks = make([]RankWithScore, len(samples))
+	for i, rank := range samples {
+		node, index := tree.findFromRight(tree.count - first - rank - 1, false)
+		ranks[i] = RankWithScore{node.members[index], node.score}
+	}
+	return
+}
+
+
+// Returns up to <n> distinct random ranks in [0, size), in random order.
+func (tree *RankTree) sampleRanks(size, n int) []int {
+	if n > size {
+		n = size
+	}
+
+	if n <= 0 {
+		return make([]int, 0)
+	}
+
+	// Floyd's algorithm
+	ranks := make([]int, 0, n)
+	chosen := make(map[int]bool, n)
+	for j := size - n; j < size; j++ {
+		r := tree.intn(j + 1)
+		if chosen[r] {
+			r = j
+		}
+		chosen[r] = true
+		ranks = append(ranks, r)
+	}
+
+	// shuffle
+	for i := len(ranks) - 1; i > 0; i-- {
+		j := tree.intn(i + 1)
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	return ranks
+}
+
+
+// Returns a random number in [0, n).
+func (tree *RankTree) intn(n int) int {
+	if tree.rand != nil {
+		return tree.rand.Intn(n)
+	}
+	return rand.Intn(n)
+}
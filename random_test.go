@@ -0,0 +1,100 @@
+package ranktree
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+
+func newRandomTestTree(t *testing.T) *RankTree {
+	tree, err := New(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 50; i++ {
+		tree.Add(fmt.Sprintf("m%d", i), i * 2)
+	}
+	tree.SetRandSource(rand.NewSource(1))
+	return tree
+}
+
+
+func TestRankTree_RandomMember(t *testing.T) {
+	tree := newRandomTestTree(t)
+
+	members, scores := tree.RandomMember(10, true)
+	if len(members) != 10 || len(scores) != 10 {
+		t.Fatalf("len(members), len(scores) = %d, %d, want 10, 10", len(members), len(scores))
+	}
+
+	seen := make(map[string]bool)
+	for i, member := range members {
+		if seen[member] {
+			t.Errorf("member %s returned twice", member)
+		}
+		seen[member] = true
+		if n := tree.Score(member); scores[i] != n {
+			t.Errorf("scores[%d] = %d, want %d", i, scores[i], n)
+		}
+	}
+
+	// reproducible
+	tree.SetRandSource(rand.NewSource(1))
+	again, _ := tree.RandomMember(10, true)
+	checkRank(t, again, members)
+
+	if members, scores = tree.RandomMember(100, false); len(members) != 50 || scores != nil {
+		t.Errorf("len(members) = %d, scores = %v, want 50, nil", len(members), scores)
+	}
+
+	if members, _ = tree.RandomMember(-100, false); len(members) != 100 {
+		t.Errorf("len(members) = %d, want 100", len(members))
+	}
+
+	empty, _ := New(0, 10)
+	if members, _ = empty.RandomMember(-3, false); len(members) != 0 {
+		t.Errorf("len(members) = %d on empty tree, want 0", len(members))
+	}
+}
+
+
+func TestRankTree_RandomMemberUniform(t *testing.T) {
+	tree := newRandomTestTree(t)
+
+	counts := make(map[string]int)
+	members, _ := tree.RandomMember(-50000, false)
+	for _, member := range members {
+		counts[member]++
+	}
+
+	for member, n := range counts {
+		if n < 800 || n > 1200 {
+			t.Errorf("%s selected %d times, want about 1000", member, n)
+		}
+	}
+}
+
+
+func TestRankTree_RandomInScoreRange(t *testing.T) {
+	tree := newRandomTestTree(t)
+
+	ranks := tree.RandomInScoreRange(10, 19, 3)
+	if len(ranks) != 3 {
+		t.Fatalf("len(ranks) = %d, want 3", len(ranks))
+	}
+	for _, v := range ranks {
+		if v.Score < 10 || v.Score > 19 || tree.Score(v.Member) != v.Score {
+			t.Errorf("rank = %v, want score in [10, 19]", v)
+		}
+	}
+
+	if ranks = tree.RandomInScoreRange(10, 19, 10); len(ranks) != 5 {
+		t.Errorf("len(ranks) = %d, want 5", len(ranks))
+	}
+
+	if ranks = tree.RandomInScoreRange(200, 300, 10); len(ranks) != 0 {
+		t.Errorf("len(ranks) = %d, want 0", len(ranks))
+	}
+}
@@ -11,49 +11,48 @@
 package ranktree
 
 import (
-	"fmt"
-	"log"
 	"errors"
+	"math"
+	"math/rand"
+	"slices"
 	"sort"
-
-	"github.com/ng1091/ranktree/list"
 )
 
 
 
-// TreeNode is an element of a RankTree.
-// If low < high, it's an internal node, if low = high, it's a leaf node.
+// TreeNode is a leaf node of a RankTree, it holds the members with the same score.
+// Internal nodes are not allocated, only their counts are stored by the backend.
 type TreeNode struct {
-	low    		int			// lower bound of the score range
-	high   		int			// upper bound of the score range
-	count  		int			// number of children
-	left		*TreeNode	// left child
-	right   	*TreeNode	// right child
-	parent		*TreeNode
-
-	element		*list.Element	// point to list.Element
+	score		int				// score of the members
+	element		int				// index of the element in the list, 0 if the node is empty
 	members 	[]string
+	gen			uint64			// generation which may write the node in place, see Snapshot()
 }
 
 
 // RankTree is a rank data structure based on binary tree.
+// The root covers the scores [low, high], the members of each score are counted by the backend.
+// A RankTree is not safe for concurrent use: calls which may modify it must not run concurrently
+// with any other call, e.g. a server holds a mutex around them. Read concurrently from a Snapshot() instead.
+// There is deliberately no locking wrapper: the servers lock their map of trees and the tree under one mutex,
+// which a lock per tree would not replace.
 type RankTree struct {
-	root		*TreeNode
-	nodeMap 	map[string]*TreeNode	// member to node
-	list		*list.List				// singly linked list
+	backend		backend					// leaf nodes and their counts, see WithFenwick(), WithSkipList()
+	low			int						// lower bound of the score range of the root
+	high		int						// upper bound of the score range of the root
+	scores		scoreMap				// member to score
+	list		leafList				// non-empty leaf nodes, from the highest score
 	count   	int						// number of members
+	gen			uint64					// generation of the leaf nodes, the list and scores, see Snapshot()
 
 	minScore	int
 	maxScore	int
+	autoExtend	bool					// see WithAutoExtend()
 	// usedMemory uint
-}
 
-
-// Rank result generator.
-type rankResultGenerator struct {
-	node *TreeNode
-	index int
-	reverse bool
+	observers	[]*observer			// change observers, see OnChange()
+	watchers	[]*boundaryWatcher	// top-K watchers, see WatchRankBoundary()
+	rand		*rand.Rand			// source of RandomMember(), nil for the default source
 }
 
 
@@ -64,9 +63,25 @@ type RankWithScore struct {
 }
 
 
+// Option configures a RankTree created by New().
+type Option func(tree *RankTree)
+
+
+// WithAutoExtend makes Add(), IncrementBy(), UpdateScore() and Apply() extend the score range
+// by ExtendRange() when a score is out of the range, instead of failing.
+// They still fail if the extended range is too large for the backend, see ErrRangeTooLarge.
+func WithAutoExtend() Option {
+	return func(tree *RankTree) {
+		tree.autoExtend = true
+	}
+}
+
+
 // New Creates a RankTree.
 // Low and high represents the score range.
-func New(low int, high int) (*RankTree, error) {
+// Without a backend option, a range too large for the default segment tree is stored in a skip list,
+// see WithSkipList(). Returns ErrRangeTooLarge if the range is too large for the chosen backend.
+func New(low int, high int, opts ...Option) (*RankTree, error) {
 	// check range
 	if low < 0 || high < 0 {
 		return nil, errors.New("low, high must be non-negative")
@@ -77,10 +92,21 @@ func New(low int, high int) (*RankTree, error) {
 	}
 
 	tree := new(RankTree)
-	tree.root = new(TreeNode)
-	tree.root.create(low, high, nil)
-	tree.nodeMap = make(map[string]*TreeNode)
-	tree.list = list.New()
+	for _, opt := range opts {
+		opt(tree)
+	}
+
+	if tree.backend == nil {
+		tree.backend = defaultBackend()
+		if rangeTooLarge(tree.backend, low, high) {
+			tree.backend = newSkipList()
+		}
+	}
+	if rangeTooLarge(tree.backend, low, high) {
+		return nil, ErrRangeTooLarge
+	}
+	tree.gen = newGen()
+	tree.create(low, high)
 	tree.minScore = low
 	tree.maxScore = high
 	return tree, nil
@@ -90,17 +116,31 @@ func New(low int, high int) (*RankTree, error) {
 // Add adds a member to RankTree.
 // If <member> exists, or <score> out of the range, false returned.
 func (tree *RankTree) Add(member string, score int) bool {
-	// member not in nodeMap
-	if _, ok := tree.nodeMap[member]; ok == false {
-		node := tree.find(score)
+	old := tree.memberState(member)
+	if tree.add(member, score) {
+		tree.changed(member, old)
+		return true
+	}
+	return false
+}
+
+
+// Adds a member to RankTree without notifying observers.
+func (tree *RankTree) add(member string, score int) bool {
+	// member not in the tree
+	if _, ok := tree.scores.get(member); ok == false {
+		node := tree.leaf(score)
+		if node == nil && tree.autoExtend && tree.ExtendRange(score, score) == nil {
+			node = tree.leaf(score)
+		}
 
 		if node != nil {
 			// create a list element
-			if node.element == nil {
+			if node.element == 0 {
 				tree.createListElement(node)
 			}
 
-			tree.nodeMap[member] = node
+			tree.scores.set(member, score, tree.gen)
 			tree.count++
 
 			node.members = append(node.members, member)
@@ -108,7 +148,7 @@ func (tree *RankTree) Add(member string, score int) bool {
 				sort.Strings(node.members)
 			}
 
-			node.incrementCount(1)
+			tree.incrementCount(score, 1)
 			return true
 		}
 	}
@@ -122,7 +162,7 @@ func (tree *RankTree) Add(member string, score int) bool {
 // The rank is 0-based, which means that the member with the lowest score has rank 0.
 // Use RevRank() to get the rank of an element with the scores ordered from high to low.
 func (tree *RankTree) Rank(member string) int {
-	if node, ok := tree.nodeMap[member]; ok == true {
+	if node, ok := tree.node(member); ok == true {
 		// offset in node.members
 		offset := 0
 		for k, v := range node.members {
@@ -131,15 +171,7 @@ func (tree *RankTree) Rank(member string) int {
 			}
 		}
 
-		sum := 0
-		for node.parent != nil {
-			thisNode := node
-			node = node.parent
-			if node.left != thisNode {
-				sum += node.left.count
-			}
-		}
-		return sum + offset
+		return tree.countLeftArea(node.score) + offset
 	}
 	return -1
 }
@@ -150,26 +182,13 @@ func (tree *RankTree) Rank(member string) int {
 // If member does not exist, -1 returned.
 // Scores ordered from high to low.
 // The rank is 0-based, which means that the member with the highest score has rank 0.
+// Lexicographical is used for members with equal score.
 // Use Rank() to get the rank of an element with the scores ordered from low to high.
 func (tree *RankTree) RevRank(member string) int {
-	if node, ok := tree.nodeMap[member]; ok == true {
+	if node, ok := tree.node(member); ok == true {
 		// offset in node.members
-		offset := 0
-		for k, v := range node.members {
-			if v == member {
-				offset = node.count - k - 1
-			}
-		}
-
-		sum := 0
-		for node.parent != nil {
-			thisNode := node
-			node = node.parent
-			if node.right != thisNode {
-				sum += node.right.count
-			}
-		}
-		return sum + offset
+		offset := sort.SearchStrings(node.members, member)
+		return tree.countRightArea(node.score) + offset
 	}
 	return -1
 }
@@ -184,8 +203,122 @@ func (tree *RankTree) Card() int {
 // Returns the score of member in the RankTree.
 // If member does not exist in the RankTree, -1 is returned.
 func (tree *RankTree) Score(member string) int {
-	if node, ok := tree.nodeMap[member]; ok == true {
-		return node.low
+	if score, ok := tree.scores.get(member); ok == true {
+		return score
+	}
+	return -1
+}
+
+
+// Returns the scores of <members> in input order.
+// ok[i] reports whether members[i] exists, if not, scores[i] is -1.
+func (tree *RankTree) MScore(members ...string) (scores []int, ok []bool) {
+	scores = make([]int, len(members))
+	ok = make([]bool, len(members))
+	for i, member := range members {
+		scores[i] = -1
+		if score, found := tree.scores.get(member); found {
+			scores[i], ok[i] = score, true
+		}
+	}
+	return
+}
+
+
+// Returns the ranks of <members> in input order, see Rank().
+// ok[i] reports whether members[i] exists, if not, ranks[i] is -1.
+// Nodes shared by members are walked only once.
+func (tree *RankTree) MRank(members ...string) (ranks []int, ok []bool) {
+	return tree.mRank(members, false)
+}
+
+
+// Returns the ranks of <members> in input order, see RevRank().
+// ok[i] reports whether members[i] exists, if not, ranks[i] is -1.
+// Nodes shared by members are walked only once.
+func (tree *RankTree) MRevRank(members ...string) (ranks []int, ok []bool) {
+	return tree.mRank(members, true)
+}
+
+
+// Basic Function of MRank(), MRevRank().
+func (tree *RankTree) mRank(members []string, reverse bool) (ranks []int, ok []bool) {
+	ranks = make([]int, len(members))
+	ok = make([]bool, len(members))
+
+	// count the left areas of the distinct scores at once
+	areas := make(map[int]int)
+	positions := make([]int, 0, len(members))
+	for _, member := range members {
+		if score, found := tree.scores.get(member); found {
+			if _, seen := areas[score]; seen == false {
+				areas[score] = 0
+				positions = append(positions, score - tree.low)
+			}
+		}
+	}
+	sort.Ints(positions)
+	for i, sum := range tree.backend.prefixAll(positions) {
+		areas[tree.low + positions[i]] = sum
+	}
+
+	for i, member := range members {
+		ranks[i] = -1
+		node, found := tree.node(member)
+		if found == false {
+			continue
+		}
+
+		index := sort.SearchStrings(node.members, member)
+		if reverse {
+			ranks[i] = tree.count - areas[node.score] - len(node.members) + index
+		} else {
+			ranks[i] = areas[node.score] + index
+		}
+		ok[i] = true
+	}
+	return
+}
+
+
+// Returns the score of the member with <rank> in the RankTree.
+// Scores ordered from low to high, see Rank().
+// If <rank> is out of the range, -1 is returned.
+func (tree *RankTree) ScoreAtRank(rank int) int {
+	return tree.ScoreAtRevRank(tree.count - rank - 1)
+}
+
+
+// Returns the score of the member with <rank> in the RankTree.
+// Scores ordered from high to low, see RevRank().
+// If <rank> is out of the range, -1 is returned.
+func (tree *RankTree) ScoreAtRevRank(rank int) int {
+	if node, _ := tree.findFromRight(rank, false); node != nil {
+		return node.score
+	}
+	return -1
+}
+
+
+// Returns the rank which a member with <score> would get in the RankTree,
+// i.e. the number of members with a score lower than <score>.
+// Scores ordered from low to high, the rank is the first one among members with equal score.
+// If <score> is out of the range, -1 is returned.
+func (tree *RankTree) RankOfScore(score int) int {
+	if score >= tree.minScore && score <= tree.maxScore {
+		return tree.countLeftArea(score)
+	}
+	return -1
+}
+
+
+// Returns the rank which a member with <score> would get in the RankTree,
+// i.e. the number of members with a score higher than <score>.
+// Scores ordered from high to low, the rank is the first one among members with equal score.
+// If <score> is out of the range, -1 is returned.
+func (tree *RankTree) RevRankOfScore(score int) int {
+	if score >= tree.minScore && score <= tree.maxScore {
+		return tree.countRightArea(score)
 	}
 	return -1
 }
@@ -205,11 +338,7 @@ func (tree *RankTree) Count(min, max int) int {
 		return 0
 	}
 
-	leftNode := tree.find(min)
-	rightNode := tree.find(max)
-	leftCount := leftNode.countLeftArea()
-	rightCount := rightNode.countLeftArea() + rightNode.count
-	return rightCount - leftCount
+	return tree.count - tree.countLeftArea(min) - tree.countRightArea(max)
 }
 
 
@@ -217,7 +346,11 @@ func (tree *RankTree) Count(min, max int) int {
 // Returns the number of members removed from the RankTree.
 func (tree *RankTree) Remove(members ...string) (sum int) {
 	for _, member := range members {
-		sum += tree.remove(member)
+		old := tree.memberState(member)
+		if tree.remove(member) > 0 {
+			tree.changed(member, old)
+			sum++
+		}
 	}
 	return
 }
@@ -226,7 +359,9 @@ func (tree *RankTree) Remove(members ...string) (sum int) {
 // Removes <member> from the RankTree.
 // If <member> exists, 1 is returned, otherwise 0 is returned.
 func (tree *RankTree) remove(member string) int {
-	if node, ok := tree.nodeMap[member]; ok == true {
+	if score, ok := tree.scores.get(member); ok == true {
+		node := tree.own(tree.backend.leaf(score - tree.low))
+
 		// remove member from node.members
 		for i, v := range node.members {
 			if v == member {
@@ -235,15 +370,13 @@ func (tree *RankTree) remove(member string) int {
 			}
 		}
 		// remove list element
-		if node.count == 1 {
-			greaterNode := tree.findNextGreaterElement(node)
-			tree.list.RemoveNext(greaterNode)
-			node.element = nil
+		if len(node.members) == 0 {
+			tree.list.remove(node, tree.gen)
 		}
 		// remove map & count
-		delete(tree.nodeMap, member)
+		tree.scores.delete(member, tree.gen)
 		tree.count--
-		node.incrementCount(-1)
+		tree.incrementCount(node.score, -1)
 		return 1
 	}
 	return 0
@@ -256,13 +389,12 @@ func (tree *RankTree) PopMax() (rank *RankWithScore) {
 		return nil
 	}
 
-	e := tree.list.Head()
-	if node, ok :=  e.Value.(*TreeNode); ok {
+	if node := tree.list.head(); node != nil {
 		rank = new(RankWithScore)
 		member := node.members[len(node.members) - 1]
-		tree.remove(member)
+		tree.Remove(member)
 		rank.Member = member
-		rank.Score = node.low
+		rank.Score = node.score
 	}
 	return
 }
@@ -274,13 +406,12 @@ func (tree *RankTree) PopMin() (rank *RankWithScore) {
 		return nil
 	}
 
-	e := tree.list.Back()
-	if node, ok :=  e.Value.(*TreeNode); ok {
+	if node := tree.list.back(); node != nil {
 		rank = new(RankWithScore)
 		member := node.members[len(node.members) - 1]
-		tree.remove(member)
+		tree.Remove(member)
 		rank.Member = member
-		rank.Score = node.low
+		rank.Score = node.score
 	}
 	return
 }
@@ -325,13 +456,20 @@ func (tree *RankTree) PopMinN(n int) (ranks []RankWithScore) {
 // Increments the score of member in the RankTree.
 // If <member> does not exist in the RankTree, it is added with <score>.
 // Returns the new score of the member.
+// If the new score overflows an int, the member is left unchanged and -1 is returned.
 func (tree *RankTree) IncrementBy(member string, score int) int {
+	old := tree.memberState(member)
+	defer tree.changed(member, old)
+
 	currentScore := score
-	if node, ok := tree.nodeMap[member]; ok == true {
-		currentScore += node.low
+	if score, ok := tree.scores.get(member); ok == true {
+		if currentScore > math.MaxInt - score {
+			return -1
+		}
+		currentScore += score
 		tree.remove(member)
 	}
-	if tree.Add(member, currentScore) {
+	if tree.add(member, currentScore) {
 		return currentScore
 	} else {
 		return -1
@@ -343,10 +481,13 @@ func (tree *RankTree) IncrementBy(member string, score int) int {
 // If <insert> is true, a new member is added when it does not exist in the RankTree.
 // Returns a bool represents whether the update is successful or not.
 func (tree *RankTree) UpdateScore(member string, score int, insert bool) bool {
+	old := tree.memberState(member)
+	defer tree.changed(member, old)
+
 	n := tree.remove(member)
 
 	if n > 0 || insert {
-		tree.Add(member, score)
+		tree.add(member, score)
 		return true
 	} else {
 		return false
@@ -356,23 +497,29 @@ func (tree *RankTree) UpdateScore(member string, score int, insert bool) bool {
 
 // Sanitize indexes of rangeBasic(), rangeWithScore().
 func (tree *RankTree) rangeSanitizeIndexes(start, end *int) (length int) {
+	return sanitizeIndexes(start, end, tree.count)
+}
+
+
+// Sanitize indexes of a range over <count> members.
+func sanitizeIndexes(start, end *int, count int) (length int) {
 	// Sanitize indexes
 	if *start < 0 {
-		if *start += tree.count; *start < 0 {
+		if *start += count; *start < 0 {
 			*start = 0
 		}
 	}
 
 	if *end < 0 {
-		*end += tree.count
+		*end += count
 	}
 
-	if *start > *end || *start >= tree.count {
+	if *start > *end || *start >= count {
 		return 0
 	}
 
-	if *end >= tree.count {
-		*end = tree.count - 1
+	if *end >= count {
+		*end = count - 1
 	}
 
 	return *end - *start + 1
@@ -392,28 +539,15 @@ func (tree *RankTree) rangeBasic(start, end int, reverse bool) []string {
 	}
 
 	result := make([]string, rangeLen)
-	var idx, skip int
-	if reverse {
-		skip = start
-		idx = 0
-	} else {
-		skip = tree.count - end - 1
-		idx = rangeLen - 1
-	}
 
 	// find first node
-	node , index := tree.findFromRight(skip, reverse)
-	gen := &rankResultGenerator{node, index, reverse}
+	it := tree.newIterator(reverse)
+	it.SeekRank(start)
 
 	// collect result from linked list
-	for i := 0; i < rangeLen; i++ {
-		result[idx] = gen.Member()
-		gen.Next()
-		if reverse {
-			idx++
-		} else {
-			idx--
-		}
+	for i := range result {
+		it.Next()
+		result[i] = it.Member()
 	}
 
 	return result
@@ -433,28 +567,15 @@ func (tree *RankTree) rangeWithScore(start, end int, reverse bool) []RankWithSco
 	}
 
 	result := make([]RankWithScore, rangeLen)
-	var idx, skip int
-	if reverse {
-		skip = start
-		idx = 0
-	} else {
-		skip = tree.count - end - 1
-		idx = rangeLen - 1
-	}
 
 	// find first node
-	node , index := tree.findFromRight(skip, reverse)
-	gen := &rankResultGenerator{node, index, reverse}
+	it := tree.newIterator(reverse)
+	it.SeekRank(start)
 
 	// collect result from linked list
-	for i := 0; i < rangeLen; i++ {
-		result[idx] = gen.RankWithScore()
-		gen.Next()
-		if reverse {
-			idx++
-		} else {
-			idx--
-		}
+	for i := range result {
+		it.Next()
+		result[i] = it.RankWithScore()
 	}
 
 	return result
@@ -493,6 +614,79 @@ func (tree *RankTree) RevRangeWithScore(start, end int) []RankWithScore {
 }
 
 
+// Returns <member> and up to <before> members ranked before it and <after> members ranked after it.
+// If reverse is false, members are ordered from the lowest to the highest score.
+// Otherwise, members are ordered from the highest to the lowest score.
+// Lexicographical is used for members with equal score.
+// <first> is the 0-based rank of ranks[0] in Range() or RevRange(), the rank of ranks[i] is first + i.
+// If member does not exist, (nil, -1) is returned.
+func (tree *RankTree) Around(member string, before, after int, reverse bool) (ranks []RankWithScore, first int) {
+	node, ok := tree.node(member)
+	if ok == false {
+		return nil, -1
+	}
+
+	if before < 0 {
+		before = 0
+	}
+
+	if after < 0 {
+		after = 0
+	}
+
+	index := sort.SearchStrings(node.members, member)
+	if reverse {
+		first = tree.countRightArea(node.score) + index
+	} else {
+		first = tree.countLeftArea(node.score) + index
+	}
+
+	// walk towards the first rank
+	ranks = make([]RankWithScore, 0, before + after + 1)
+	n, i := node, index
+	for len(ranks) < before {
+		if i > 0 {
+			i--
+		} else if n = tree.neighborNode(n, !reverse); n != nil {
+			i = len(n.members) - 1
+		} else {
+			break
+		}
+		ranks = append(ranks, RankWithScore{n.members[i], n.score})
+	}
+	first -= len(ranks)
+
+	// reverse the members before, then walk towards the last rank
+	for l, r := 0, len(ranks) - 1; l < r; l, r = l + 1, r - 1 {
+		ranks[l], ranks[r] = ranks[r], ranks[l]
+	}
+	ranks = append(ranks, RankWithScore{member, node.score})
+
+	n, i = node, index
+	for m := 0; m < after; m++ {
+		if i < len(n.members) - 1 {
+			i++
+		} else if n = tree.neighborNode(n, reverse); n != nil {
+			i = 0
+		} else {
+			break
+		}
+		ranks = append(ranks, RankWithScore{n.members[i], n.score})
+	}
+	return
+}
+
+
+// Returns the next non-empty node with a lower score if <lower> is true, otherwise with a higher score.
+// If there is no such node, nil is returned.
+func (tree *RankTree) neighborNode(node *TreeNode, lower bool) *TreeNode {
+	if lower {
+		return tree.list.next(node)
+	}
+	return tree.list.prev(node)
+}
+
+
 // Basic Function of RangeByScore(), RevRangeByScore().
 // Returns all the members in the RankTree with a score between min and max.
 // If reverse is false, members are ordered from the lowest to the highest score.
@@ -512,34 +706,24 @@ func (tree *RankTree) rangeByScoreBasic(min, max int, reverse bool) (ranks []Ran
 		return
 	}
 
-
-	node := tree.find(max)
-	if node.count == 0 {
-		node = tree.findNextSmallerNode(node)
+	length := tree.Count(min, max)
+	ranks = make([]RankWithScore, length)
+	if length == 0 {
+		return
 	}
 
-	if node != nil {
-		length := tree.Count(min, max)
-		ranks = make([]RankWithScore, length)
-		var idx, index int
-		if reverse == false {
-			idx = length - 1
-			index = node.count - 1
-		}
-		gen := &rankResultGenerator{node, index, reverse}
-
+	// skip members out of the range
+	it := tree.newIterator(reverse)
+	if reverse {
+		it.SeekScore(max)
+	} else {
+		it.SeekScore(min)
+	}
 
-		for i := 0; i < length; i++ {
-			ranks[idx] = gen.RankWithScore()
-			gen.Next()
-			if reverse {
-				idx++
-			} else {
-				idx--
-			}
-		}
+	for i := range ranks {
+		it.Next()
+		ranks[i] = it.RankWithScore()
 	}
-	fmt.Println(ranks)
 	return
 }
 
@@ -562,156 +746,103 @@ func (tree *RankTree) RevRangeByScore(min, max int) (ranks []RankWithScore) {
 
 // Adds a node element to the linked list.
 func (tree *RankTree) createListElement(node *TreeNode) {
-	// node must be leaf node
-	if node.low != node.high {
-		return
-	}
-
 	if tree.count == 0 {
-		e := tree.list.PushFront(node)
-		node.element = e
+		tree.list.insertAfter(node, nil, tree.gen)
 	} else if tree.count < 10 { // Linear Search Threshold = 10
 		// Linear Search  O(N)
-		var target *list.Element
-		n := node.low
-		e := tree.list.Head()
-		for e != nil {
-			v := e.Value.(*TreeNode)
-			if v.low >= n {
-				target = e
-			} else {
-				break
-			}
-			e = e.Next()
+		var target *TreeNode
+		for v := tree.list.head(); v != nil && v.score >= node.score; v = tree.list.next(v) {
+			target = v
 		}
 
-		// node is the greatest
-		if target == nil {
-			e := tree.list.PushFront(node)
-			node.element = e
-		} else { // node is less than target
-			e := tree.list.InsertAfter(node, target)
-			node.element = e
-		}
+		// node is the greatest if target is nil, otherwise node is less than target
+		tree.list.insertAfter(node, target, tree.gen)
 	} else {
 		// Tree Search O(LogN)
-		target := tree.findNextGreaterElement(node)
-		e := tree.list.InsertAfter(node, target)
-		node.element = e
+		tree.list.insertAfter(node, tree.findNextGreaterNode(node.score), tree.gen)
 	}
 }
 
 
-// Find a next greater node in the RankTree.
-// If <node> is the greatest, nil is returned.
-func (tree *RankTree) findNextGreaterNode(node *TreeNode) *TreeNode {
-	count := node.count
-	p := node.parent
-	var target *TreeNode = nil
+// Find a next non-empty node with a score greater than <score> in the RankTree.
+// If there is no such node, nil is returned.
+func (tree *RankTree) findNextGreaterNode(score int) *TreeNode {
+	// number of members with a score not greater than <score>
+	n := tree.count - tree.countRightArea(score)
 
-	for p != nil {
-		if p.left == node && p.count > count {
-			target = p
-			break
-		}
-		count = p.count
-		node = p
-		p = p.parent
-	}
-
-
-	// node is the greatest
-	if target == nil {
+	// <score> is the greatest
+	if n == tree.count {
 		return nil
-	} else {
-		// find next greater
-		n := p.right
-		for n.low != n.high {
-			if n.left.count > 0 {
-				n = n.left
-			} else if n.right.count > 0 {
-				n = n.right
-			} else {
-				log.Fatal("findNextGreaterElement left,right=0")
-			}
-		}
-		return n
 	}
+	next, _ := tree.findFromRight(tree.count - n - 1, false)
+	return next
 }
 
 
-// Find a next smaller node in the RankTree.
-// If <node> is the smallest, nil is returned.
-func (tree *RankTree) findNextSmallerNode(node *TreeNode) *TreeNode {
-	count := node.count
-	p := node.parent
-	var target *TreeNode
+// Find a next non-empty node with a score less than <score> in the RankTree.
+// If there is no such node, nil is returned.
+func (tree *RankTree) findNextSmallerNode(score int) *TreeNode {
+	// number of members with a score less than <score>
+	n := tree.countLeftArea(score)
 
-	for p != nil {
-		if p.right == node && p.count > count {
-			target = p
-			break
-		}
-		count = p.count
-		node = p
-		p = p.parent
+	// <score> is the smallest
+	if n == 0 {
+		return nil
 	}
+	prev, _ := tree.findFromRight(tree.count - n, false)
+	return prev
+}
+
 
-	// node is the smallest
-	if target == nil {
+// Find a node with <score>.
+// If the node has never been used or <score> is out of the range, nil is returned.
+func (tree *RankTree) find(score int) *TreeNode {
+	if score < tree.minScore || score > tree.maxScore {
 		return nil
-	} else {
-		n := p.left
-		for n.low != n.high {
-			if n.right.count > 0 {
-				n = n.right
-			} else if n.left.count > 0 {
-				n = n.left
-			} else {
-				log.Fatal("findNextSmallerNode left,right=0")
-			}
-		}
-		return n
 	}
+	return tree.backend.leaf(score - tree.low)
 }
 
 
-// Find a next greater element of the list.
-// If the element of <node> is the greatest node, the root element of the list is returned.
-func (tree *RankTree) findNextGreaterElement(node *TreeNode) *list.Element {
-	n := tree.findNextGreaterNode(node)
+// Find a node with <score> to modify it, the node is created if it has never been used,
+// or copied if it is shared with a snapshot, see own().
+// If <score> is out of the range, nil is returned.
+func (tree *RankTree) leaf(score int) *TreeNode {
+	if score < tree.minScore || score > tree.maxScore {
+		return nil
+	}
 
-	if n == nil {
-		return tree.list.Root()
+	node := tree.backend.leaf(score - tree.low)
+	if node == nil {
+		node = &TreeNode{score: score, gen: tree.gen}
+		tree.backend.setLeaf(score - tree.low, node)
 	}
-	return n.element
+	return tree.own(node)
 }
 
 
-// Find a node with <score>.
-// If the node does not exist or <score> is out of the range, nil is returned.
-func (tree *RankTree) find(score int) *TreeNode {
-	if tree.root == nil || score < tree.minScore || score > tree.maxScore {
-		return nil
+// Returns <node> to modify it. If it belongs to another generation, i.e. it is shared with a snapshot,
+// a copy of it replaces it in the backend and the list, and is returned instead.
+func (tree *RankTree) own(node *TreeNode) *TreeNode {
+	if node.gen == tree.gen {
+		return node
 	}
 
-	node := tree.root
-	for  {
-		low, high := node.low, node.high
-		if low == high {
-			if low == score {
-				return node
-			}
-			return nil
-		} else {
-			mid := (low + high) / 2
-			if score <= mid {
-				node = node.left
-			} else {
-				node = node.right
-			}
-		}
+	c := &TreeNode{score: node.score, element: node.element, members: slices.Clone(node.members), gen: tree.gen}
+	tree.backend.setLeaf(c.score - tree.low, c)
+	if c.element != 0 {
+		tree.list.replace(c, tree.gen)
 	}
+	return c
+}
+
+
+// Returns the leaf node of <member>, and whether the member exists.
+func (tree *RankTree) node(member string) (node *TreeNode, ok bool) {
+	if score, ok := tree.scores.get(member); ok {
+		return tree.backend.leaf(score - tree.low), true
+	}
+	return nil, false
 }
 
 
@@ -724,121 +855,66 @@ func (tree *RankTree) findFromRight(count int, reverse bool) (node *TreeNode, in
 		return nil, 0
 	}
 
-	node = tree.root
-	skip := count
-
-	for node.low < node.high {
-		if node.right.count > 0 && skip < node.right.count {
-			node = node.right
-		} else {
-			if skip > 0 { // && node.right.count > 0
-				skip -= node.right.count
-			}
-			node = node.left
-		}
-	}
+	pos, offset := tree.backend.search(tree.count - count - 1)
+	node = tree.backend.leaf(pos)
 
 	if reverse { // index start from left to right
-		index = skip
+		index = len(node.members) - offset - 1
 	} else {  // index start from right to left
-		index = node.count - skip - 1
+		index = offset
 	}
 
 	return node, index
 }
 
 
-// Increases the count of the node and the parents by <delta>.
-func (node *TreeNode) incrementCount(delta int) {
-	for {
-		node.count += delta
-		node = node.parent
-		if node == nil {
-			break
-		}
-	}
+// Increases the count of the leaf node with <score> by <delta>.
+func (tree *RankTree) incrementCount(score int, delta int) {
+	tree.backend.add(score - tree.low, delta)
 }
 
 
-// Returns count of the left area.
-func (node *TreeNode) countLeftArea() (sum int) {
-	for node.parent != nil {
-		thisNode := node
-		node = node.parent
-		if node.left != thisNode {
-			sum += node.left.count
-		}
+// Increases the counts of the leaf nodes with <scores> by deltas[score].
+// <scores> must be sorted.
+func (tree *RankTree) incrementCounts(scores []int, deltas map[int]int) {
+	positions := make([]int, len(scores))
+	values := make([]int, len(scores))
+	for i, score := range scores {
+		positions[i] = score - tree.low
+		values[i] = deltas[score]
 	}
-	return
+	tree.backend.addAll(positions, values)
 }
 
 
-// Returns count of the right area.
-func (node *TreeNode) countRightArea() (sum int) {
-	for node.parent != nil {
-		thisNode := node
-		node = node.parent
-		if node.right != thisNode {
-			sum += node.right.count
-		}
-	}
-	return
-}
-
-
-// Creates children nodes for <parent>.
-func (node *TreeNode) create(low int, high int, parent *TreeNode) {
-	node.low = low
-	node.high = high
-	node.parent = parent
-
-	if low < high {
-		mid := (low + high) / 2
-		node.left = new(TreeNode)
-		node.right = new(TreeNode)
-		node.left.create(low, mid, node)
-		node.right.create(mid + 1, high, node)
+// Returns the number of members with a score less than <score>.
+func (tree *RankTree) countLeftArea(score int) int {
+	if score > tree.high {
+		return tree.count
 	}
+	return tree.backend.prefix(score - tree.low)
 }
 
 
-// Next result.
-func (r *rankResultGenerator) Next() {
-	if r.reverse {
-		if r.index < r.node.count - 1 {
-			r.index++
-		} else {
-			e := r.node.element
-			if e = e.Next(); e != nil {
-				r.node = e.Value.(*TreeNode)
-				r.index = 0
-			}
-		}
-	} else {
-		if r.index > 0 {
-			r.index--
-		} else {
-			e := r.node.element
-			if e = e.Next(); e != nil {
-				r.node = e.Value.(*TreeNode)
-				r.index = r.node.count - 1
-			}
-		}
+// Returns the number of members with a score greater than <score>.
+func (tree *RankTree) countRightArea(score int) int {
+	if score >= tree.high {
+		return 0
 	}
+	return tree.count - tree.countLeftArea(score + 1)
 }
 
 
-// Returns member of the result node.
-func (r *rankResultGenerator) Member() string {
-	return r.node.members[r.index]
-}
+// Creates empty nodes for the score range [low, high].
+func (tree *RankTree) create(low int, high int) {
+	tree.low = low
+	tree.high = high
 
-// Returns rank and score of the result node.
-func (r *rankResultGenerator) RankWithScore() RankWithScore {
-	return RankWithScore{
-		Member: r.node.members[r.index],
-		Score: r.node.low }
+	// [0, math.MaxInt] has one more score than an int can count, only the skip list accepts it,
+	// which does not grow beyond math.MaxInt anyway
+	size := high - low + 1
+	if high - low == math.MaxInt {
+		size = math.MaxInt
+	}
+	tree.backend.reset(size)
 }
-
-
-
@@ -2,7 +2,7 @@ package ranktree
 
 import (
 	"testing"
-	"container/list"
+	"fmt"
 )
 
 
@@ -12,41 +12,26 @@ func checkRankTree(t *testing.T, tree *RankTree, low, high, count int) {
 		t.Errorf("tree.count=%d, want %d", n, count)
 	}
 
-	if n := len(tree.nodeMap); n != count {
-		t.Errorf("len(tree.nodeMap)=%d, want %d", n, count)
+	if n := tree.scores.len; n != count {
+		t.Errorf("tree.scores.len=%d, want %d", n, count)
 	}
 
 
 	// check leaf node
 
-	checkValue := low
-	stack := list.New()
-	n := tree.root
-	calcCount := 0
+	if tree.low > low || tree.high < high {
+		t.Errorf("tree range = (%d, %d), want (%d, %d)", tree.low, tree.high, low, high)
+	}
 
-	for stack.Len() > 0 || n != nil {
-		for n != nil {
-			stack.PushBack(n)
-			n = n.left
+	calcCount := 0
+	for i := 0; i <= tree.high - tree.low; i++ {
+		n := tree.backend.leaf(i)
+		if n == nil {
+			continue
 		}
-
-		if stack.Len() > 0 {
-			e := stack.Back()
-			stack.Remove(e)
-			var ok bool
-			if n, ok = e.Value.(*TreeNode); ok {
-				if n.low == n.high {
-					calcCount += n.count
-					if n.low != checkValue {
-						t.Errorf("%p, node.low = %d, want %d", n, n.low, checkValue)
-					}
-					//t.Logf("%p, node.low = %d", n, checkValue)
-					checkValue++
-				}
-				n = n.right
-			}  else {
-				t.Errorf("node %p is not *TreeNode, %#v", n, n)
-			}
+		calcCount += len(n.members)
+		if n.score != tree.low + i {
+			t.Errorf("%p, node.score = %d, want %d", n, n.score, tree.low + i)
 		}
 	}
 
@@ -59,6 +44,10 @@ func checkRankTree(t *testing.T, tree *RankTree, low, high, count int) {
 	if calcCount != count {
 		t.Errorf("calcCount = %d, want %d", calcCount, count)
 	}
+
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
 }
 
 
@@ -116,24 +105,25 @@ func TestRankTree_Add(t *testing.T) {
 
 
 func checkListNode(t *testing.T, tree *RankTree, list []*TreeNode) {
-	l := tree.list.Root()
+	var l *TreeNode
 
-	if n := tree.list.Len(); n != len(list) {
-		t.Errorf("tree.list.Len() = %d, want %d", n, len(list))
+	if n := tree.list.len; n != len(list) {
+		t.Errorf("tree.list.len = %d, want %d", n, len(list))
 	}
 
 	for i := len(list) - 1; i > 0; i-- {
-		l = l.Next()
 		if l == nil {
-			t.Error("l.Next() = nil")
+			l = tree.list.head()
+		} else {
+			l = tree.list.next(l)
+		}
+		if l == nil {
+			t.Error("tree.list.next() = nil")
+			return
 		}
 
-		if n, ok := l.Value.(*TreeNode); ok {
-			if list[i] != n {
-				t.Errorf("tree.list[%d] = %d (%p), want %d (%p)", len(list) - i - 1, n.low, n, list[i].low, list[i])
-			}
-		} else {
-			t.Errorf("%p, l.Value.(*TreeNode) failed", l.Value)
+		if list[i] != l {
+			t.Errorf("tree.list[%d] = %d (%p), want %d (%p)", len(list) - i - 1, l.score, l, list[i].score, list[i])
 		}
 	}
 }
@@ -454,26 +444,29 @@ func TestRankTree_RevRank(t *testing.T) {
 	tree.Add("e", 5)
 
 
-	if n := tree.RevRank("e"); n != 0 {
-		t.Errorf("tree.Rank(\"%s\") = %d, want 0", "e", n)
+	// equal scores in the order of RevRange()
+	if n := tree.RevRank("d"); n != 0 {
+		t.Errorf("tree.Rank(\"%s\") = %d, want 0", "d", n)
 	}
 
-	if n := tree.RevRank("d"); n != 1 {
-		t.Errorf("tree.Rank(\"%s\") = %d, want 1", "d", n)
+	if n := tree.RevRank("e"); n != 1 {
+		t.Errorf("tree.Rank(\"%s\") = %d, want 1", "e", n)
 	}
 
 	if n := tree.RevRank("c"); n != 2 {
 		t.Errorf("tree.Rank(\"%s\") = %d, want 2", "c", n)
 	}
 
-	if n := tree.RevRank("b"); n != 3 {
-		t.Errorf("tree.Rank(\"%s\") = %d, want 3", "b", n)
+	if n := tree.RevRank("a"); n != 3 {
+		t.Errorf("tree.Rank(\"%s\") = %d, want 3", "a", n)
 	}
 
-	if n := tree.RevRank("a"); n != 4 {
-		t.Errorf("tree.Rank(\"%s\") = %d, want 4", "a", n)
+	if n := tree.RevRank("b"); n != 4 {
+		t.Errorf("tree.Rank(\"%s\") = %d, want 4", "b", n)
 	}
 
+	checkRank(t, tree.RevRange(0, -1), []string{"d", "e", "c", "a", "b"})
+
 	if n := tree.RevRank("f"); n != -1 {
 		t.Errorf("tree.Rank(\"%s\") = %d, want -1", "f", n)
 	}
@@ -691,3 +684,201 @@ func TestRankTree_Remove(t *testing.T) {
 
 
 
+
+
+func TestRankTree_Around(t *testing.T) {
+	tree, err := New(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 1)
+	tree.Add("b", 2)
+	tree.Add("b2", 2)
+	tree.Add("c", 4)
+	tree.Add("d", 5)
+	tree.Add("e", 6)
+	tree.Add("e2", 6)
+	tree.Add("f", 8)
+
+	ranks, first := tree.Around("c", 2, 2, false)
+	checkRankWithScore(t, ranks, []string{"b", "b2", "c", "d", "e"}, []int{2, 2, 4, 5, 6})
+	if first != 1 {
+		t.Errorf("first = %d, want %d", first, 1)
+	}
+
+	ranks, first = tree.Around("c", 2, 2, true)
+	checkRankWithScore(t, ranks, []string{"e2", "d", "c", "b", "b2"}, []int{6, 5, 4, 2, 2})
+	if first != 2 {
+		t.Errorf("first = %d, want %d", first, 2)
+	}
+
+	ranks, first = tree.Around("b2", 3, 1, false)
+	checkRankWithScore(t, ranks, []string{"a", "b", "b2", "c"}, []int{1, 2, 2, 4})
+	if first != 0 {
+		t.Errorf("first = %d, want %d", first, 0)
+	}
+
+	ranks, first = tree.Around("e", 1, 5, true)
+	checkRankWithScore(t, ranks, []string{"f", "e", "e2", "d", "c", "b", "b2"}, []int{8, 6, 6, 5, 4, 2, 2})
+	if first != 0 {
+		t.Errorf("first = %d, want %d", first, 0)
+	}
+
+	ranks, first = tree.Around("f", 0, 3, false)
+	checkRankWithScore(t, ranks, []string{"f"}, []int{8})
+	if first != 7 {
+		t.Errorf("first = %d, want %d", first, 7)
+	}
+
+	if ranks, first = tree.Around("g", 1, 1, false); ranks != nil || first != -1 {
+		t.Errorf("tree.Around(\"g\") = %v, %d, want nil, -1", ranks, first)
+	}
+}
+
+
+func TestRankTree_ScoreAtRank(t *testing.T) {
+	tree, err := New(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 1)
+	tree.Add("b", 3)
+	tree.Add("c", 3)
+	tree.Add("d", 5)
+
+	for i, want := range []int{1, 3, 3, 5, -1} {
+		if n := tree.ScoreAtRank(i); n != want {
+			t.Errorf("tree.ScoreAtRank(%d) = %d, want %d", i, n, want)
+		}
+	}
+
+	for i, want := range []int{5, 3, 3, 1, -1} {
+		if n := tree.ScoreAtRevRank(i); n != want {
+			t.Errorf("tree.ScoreAtRevRank(%d) = %d, want %d", i, n, want)
+		}
+	}
+
+	if n := tree.ScoreAtRank(-1); n != -1 {
+		t.Errorf("tree.ScoreAtRank(-1) = %d, want %d", n, -1)
+	}
+}
+
+
+func TestRankTree_RankOfScore(t *testing.T) {
+	tree, err := New(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 1)
+	tree.Add("b", 3)
+	tree.Add("c", 3)
+	tree.Add("d", 5)
+
+	tests := []struct {
+		score	int
+		rank	int
+		revRank	int
+	}{
+		{1, 0, 3},
+		{2, 1, 3},
+		{3, 1, 1},
+		{4, 3, 1},
+		{5, 3, 0},
+		{8, 4, 0},
+		{0, -1, -1},
+		{9, -1, -1},
+	}
+	for _, tt := range tests {
+		if n := tree.RankOfScore(tt.score); n != tt.rank {
+			t.Errorf("tree.RankOfScore(%d) = %d, want %d", tt.score, n, tt.rank)
+		}
+		if n := tree.RevRankOfScore(tt.score); n != tt.revRank {
+			t.Errorf("tree.RevRankOfScore(%d) = %d, want %d", tt.score, n, tt.revRank)
+		}
+	}
+}
+
+
+func TestRankTree_MRank(t *testing.T) {
+	tree, err := New(1, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	members := make([]string, 0, 101)
+	for i := 0; i < 100; i++ {
+		member := fmt.Sprintf("m%d", i)
+		tree.Add(member, i * 7 % 256 + 1)
+		members = append(members, member)
+	}
+	tree.Add("m100", 8) // equal score
+	members = append(members, "m100", "x")
+
+	scores, ok := tree.MScore(members...)
+	ranks, rankOk := tree.MRank(members...)
+	revRanks, revRankOk := tree.MRevRank(members...)
+
+	for i, member := range members {
+		exists := member != "x"
+		if ok[i] != exists || rankOk[i] != exists || revRankOk[i] != exists {
+			t.Errorf("%s: ok = %v, %v, %v, want %v", member, ok[i], rankOk[i], revRankOk[i], exists)
+		}
+		if n := tree.Score(member); scores[i] != n {
+			t.Errorf("scores[%d] = %d, want %d", i, scores[i], n)
+		}
+		if n := tree.Rank(member); ranks[i] != n {
+			t.Errorf("ranks[%d] = %d, want %d", i, ranks[i], n)
+		}
+		if n := tree.RevRank(member); revRanks[i] != n {
+			t.Errorf("revRanks[%d] = %d, want %d", i, revRanks[i], n)
+		}
+	}
+}
+
+
+func TestRankTree_RemoveList(t *testing.T) {
+	tree, err := New(1, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 64; i++ {
+		tree.Add(fmt.Sprintf("m%d", i), i % 32 + 1)
+	}
+
+	for i := 0; i < 64; i += 3 {
+		tree.Remove(fmt.Sprintf("m%d", i))
+		checkListOrder(t, tree)
+	}
+
+	for tree.Card() > 0 {
+		tree.PopMin()
+		checkListOrder(t, tree)
+		tree.PopMax()
+		checkListOrder(t, tree)
+	}
+}
+
+
+func TestNew_Allocs(t *testing.T) {
+	// nodes are stored in arrays, not allocated one by one
+	allocs := testing.AllocsPerRun(10, func() {
+		New(0, 1000000)
+	})
+	if allocs > 10 {
+		t.Errorf("New(0, 1000000) allocs = %v, want <= 10", allocs)
+	}
+
+	tree, _ := New(0, 1000000)
+	tree.Add("a", 500000)
+	tree.Add("b", 1000000)
+	tree.Add("c", 0)
+	checkTreeCounts(t, tree)
+	checkRank(t, tree.Range(0, -1), []string{"c", "a", "b"})
+	if n := tree.Count(1, 1000000); n != 2 {
+		t.Errorf("tree.Count(1, 1000000) = %d, want %d", n, 2)
+	}
+}
@@ -0,0 +1,141 @@
+package ranktreegrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// Client calls a RankService with the types of RankTree.
+// Errors are gRPC status errors, e.g. codes.NotFound for a missing board or member.
+type Client struct {
+	rpc		RankServiceClient
+}
+
+
+// NewClient creates a Client on <conn>, which is owned by the caller.
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{NewRankServiceClient(conn)}
+}
+
+
+// Add adds <member> to <board>, returns false if it exists.
+func (c *Client) Add(ctx context.Context, board, member string, score int) (bool, error) {
+	resp, err := c.rpc.Add(ctx, &AddRequest{Board: board, Member: member, Score: int64(score)})
+	if err != nil {
+		return false, err
+	}
+	return resp.Added, nil
+}
+
+
+// IncrementBy increments the score of <member> in <board> by <increment>, returns the new score.
+func (c *Client) IncrementBy(ctx context.Context, board, member string, increment int) (int, error) {
+	resp, err := c.rpc.IncrementBy(ctx, &IncrementByRequest{Board: board, Member: member, Increment: int64(increment)})
+	if err != nil {
+		return -1, err
+	}
+	return int(resp.Score), nil
+}
+
+
+// Rank returns the rank and the score of <member> in <board>, see RankTree.Rank().
+func (c *Client) Rank(ctx context.Context, board, member string) (rank, score int, err error) {
+	return c.rank(ctx, board, member, false)
+}
+
+
+// RevRank returns the rank and the score of <member> in <board>, see RankTree.RevRank().
+func (c *Client) RevRank(ctx context.Context, board, member string) (rank, score int, err error) {
+	return c.rank(ctx, board, member, true)
+}
+
+
+func (c *Client) rank(ctx context.Context, board, member string, reverse bool) (rank, score int, err error) {
+	resp, err := c.rpc.Rank(ctx, &RankRequest{Board: board, Member: member, Reverse: reverse})
+	if err != nil {
+		return -1, -1, err
+	}
+	return int(resp.Rank), int(resp.Score), nil
+}
+
+
+// RevRange returns the members of <board> ranked [start, end] from the highest score, see RankTree.RevRangeWithScore().
+func (c *Client) RevRange(ctx context.Context, board string, start, end int) ([]ranktree.RankWithScore, error) {
+	resp, err := c.rpc.RevRange(ctx, &RevRangeRequest{Board: board, Start: int64(start), End: int64(end)})
+	if err != nil {
+		return nil, err
+	}
+	return ranks(resp.Entries), nil
+}
+
+
+// RangeByScore returns the members of <board> with scores in [min, max],
+// from the lowest score, or from the highest score if <reverse> is true.
+func (c *Client) RangeByScore(ctx context.Context, board string, min, max int, reverse bool) ([]ranktree.RankWithScore, error) {
+	resp, err := c.rpc.RangeByScore(ctx, &RangeByScoreRequest{Board: board, Min: int64(min), Max: int64(max), Reverse: reverse})
+	if err != nil {
+		return nil, err
+	}
+	return ranks(resp.Entries), nil
+}
+
+
+// PopMax removes and returns up to <n> members with the highest scores in <board>.
+func (c *Client) PopMax(ctx context.Context, board string, n int) ([]ranktree.RankWithScore, error) {
+	return c.pop(ctx, board, n, false)
+}
+
+
+// PopMin removes and returns up to <n> members with the lowest scores in <board>.
+func (c *Client) PopMin(ctx context.Context, board string, n int) ([]ranktree.RankWithScore, error) {
+	return c.pop(ctx, board, n, true)
+}
+
+
+func (c *Client) pop(ctx context.Context, board string, n int, min bool) ([]ranktree.RankWithScore, error) {
+	if n <= 0 {
+		return []ranktree.RankWithScore{}, nil
+	}
+
+	resp, err := c.rpc.Pop(ctx, &PopRequest{Board: board, Count: int64(n), Min: min})
+	if err != nil {
+		return nil, err
+	}
+	return ranks(resp.Entries), nil
+}
+
+
+// WatchTop calls <fn> with the top <k> members of <board>, once on start and then after each change of them,
+// until <ctx> is canceled or the stream fails. Returns nil if <ctx> is canceled.
+func (c *Client) WatchTop(ctx context.Context, board string, k int, fn func(top []ranktree.RankWithScore)) error {
+	stream, err := c.rpc.WatchTop(ctx, &WatchTopRequest{Board: board, K: int64(k)})
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		fn(ranks(resp.Entries))
+	}
+}
+
+
+func ranks(entries []*Entry) []ranktree.RankWithScore {
+	ranks := make([]ranktree.RankWithScore, len(entries))
+	for i, e := range entries {
+		ranks[i] = ranktree.RankWithScore{Member: e.Member, Score: int(e.Score)}
+	}
+	return ranks
+}
@@ -0,0 +1,932 @@
+// RankService serves named RankTrees (boards).
+//
+// Ranks are 0-based like RankTree, a missing board or member is reported
+// as NOT_FOUND, a score out of the range of the board as OUT_OF_RANGE.
+//
+// Regenerate the Go code after changing this file:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	       --go-grpc_out=. --go-grpc_opt=paths=source_relative rankservice.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: rankservice.proto
+
+package ranktreegrpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Entry is a member with its score and rank.
+type Entry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Member        string                 `protobuf:"bytes,1,opt,name=member,proto3" json:"member,omitempty"`
+	Score         int64                  `protobuf:"varint,2,opt,name=score,proto3" json:"score,omitempty"`
+	Rank          int64                  `protobuf:"varint,3,opt,name=rank,proto3" json:"rank,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Entry) Reset() {
+	*x = Entry{}
+	mi := &file_rankservice_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entry) ProtoMessage() {}
+
+func (x *Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entry.ProtoReflect.Descriptor instead.
+func (*Entry) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Entry) GetMember() string {
+	if x != nil {
+		return x.Member
+	}
+	return ""
+}
+
+func (x *Entry) GetScore() int64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Entry) GetRank() int64 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+type AddRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Board         string                 `protobuf:"bytes,1,opt,name=board,proto3" json:"board,omitempty"`
+	Member        string                 `protobuf:"bytes,2,opt,name=member,proto3" json:"member,omitempty"`
+	Score         int64                  `protobuf:"varint,3,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddRequest) Reset() {
+	*x = AddRequest{}
+	mi := &file_rankservice_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddRequest) ProtoMessage() {}
+
+func (x *AddRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddRequest.ProtoReflect.Descriptor instead.
+func (*AddRequest) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AddRequest) GetBoard() string {
+	if x != nil {
+		return x.Board
+	}
+	return ""
+}
+
+func (x *AddRequest) GetMember() string {
+	if x != nil {
+		return x.Member
+	}
+	return ""
+}
+
+func (x *AddRequest) GetScore() int64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+type AddResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// false if the member exists
+	Added         bool `protobuf:"varint,1,opt,name=added,proto3" json:"added,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddResponse) Reset() {
+	*x = AddResponse{}
+	mi := &file_rankservice_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddResponse) ProtoMessage() {}
+
+func (x *AddResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddResponse.ProtoReflect.Descriptor instead.
+func (*AddResponse) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AddResponse) GetAdded() bool {
+	if x != nil {
+		return x.Added
+	}
+	return false
+}
+
+type IncrementByRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Board         string                 `protobuf:"bytes,1,opt,name=board,proto3" json:"board,omitempty"`
+	Member        string                 `protobuf:"bytes,2,opt,name=member,proto3" json:"member,omitempty"`
+	Increment     int64                  `protobuf:"varint,3,opt,name=increment,proto3" json:"increment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IncrementByRequest) Reset() {
+	*x = IncrementByRequest{}
+	mi := &file_rankservice_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IncrementByRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncrementByRequest) ProtoMessage() {}
+
+func (x *IncrementByRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncrementByRequest.ProtoReflect.Descriptor instead.
+func (*IncrementByRequest) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *IncrementByRequest) GetBoard() string {
+	if x != nil {
+		return x.Board
+	}
+	return ""
+}
+
+func (x *IncrementByRequest) GetMember() string {
+	if x != nil {
+		return x.Member
+	}
+	return ""
+}
+
+func (x *IncrementByRequest) GetIncrement() int64 {
+	if x != nil {
+		return x.Increment
+	}
+	return 0
+}
+
+type IncrementByResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Score         int64                  `protobuf:"varint,1,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IncrementByResponse) Reset() {
+	*x = IncrementByResponse{}
+	mi := &file_rankservice_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IncrementByResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncrementByResponse) ProtoMessage() {}
+
+func (x *IncrementByResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncrementByResponse.ProtoReflect.Descriptor instead.
+func (*IncrementByResponse) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *IncrementByResponse) GetScore() int64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+type RankRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Board  string                 `protobuf:"bytes,1,opt,name=board,proto3" json:"board,omitempty"`
+	Member string                 `protobuf:"bytes,2,opt,name=member,proto3" json:"member,omitempty"`
+	// rank from the highest score, like RankTree.RevRank()
+	Reverse       bool `protobuf:"varint,3,opt,name=reverse,proto3" json:"reverse,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RankRequest) Reset() {
+	*x = RankRequest{}
+	mi := &file_rankservice_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RankRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RankRequest) ProtoMessage() {}
+
+func (x *RankRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RankRequest.ProtoReflect.Descriptor instead.
+func (*RankRequest) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RankRequest) GetBoard() string {
+	if x != nil {
+		return x.Board
+	}
+	return ""
+}
+
+func (x *RankRequest) GetMember() string {
+	if x != nil {
+		return x.Member
+	}
+	return ""
+}
+
+func (x *RankRequest) GetReverse() bool {
+	if x != nil {
+		return x.Reverse
+	}
+	return false
+}
+
+type RankResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rank          int64                  `protobuf:"varint,1,opt,name=rank,proto3" json:"rank,omitempty"`
+	Score         int64                  `protobuf:"varint,2,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RankResponse) Reset() {
+	*x = RankResponse{}
+	mi := &file_rankservice_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RankResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RankResponse) ProtoMessage() {}
+
+func (x *RankResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RankResponse.ProtoReflect.Descriptor instead.
+func (*RankResponse) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RankResponse) GetRank() int64 {
+	if x != nil {
+		return x.Rank
+	}
+	return 0
+}
+
+func (x *RankResponse) GetScore() int64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+type RevRangeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Board         string                 `protobuf:"bytes,1,opt,name=board,proto3" json:"board,omitempty"`
+	Start         int64                  `protobuf:"varint,2,opt,name=start,proto3" json:"start,omitempty"`
+	End           int64                  `protobuf:"varint,3,opt,name=end,proto3" json:"end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevRangeRequest) Reset() {
+	*x = RevRangeRequest{}
+	mi := &file_rankservice_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevRangeRequest) ProtoMessage() {}
+
+func (x *RevRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevRangeRequest.ProtoReflect.Descriptor instead.
+func (*RevRangeRequest) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RevRangeRequest) GetBoard() string {
+	if x != nil {
+		return x.Board
+	}
+	return ""
+}
+
+func (x *RevRangeRequest) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *RevRangeRequest) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+type RangeByScoreRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Board string                 `protobuf:"bytes,1,opt,name=board,proto3" json:"board,omitempty"`
+	Min   int64                  `protobuf:"varint,2,opt,name=min,proto3" json:"min,omitempty"`
+	Max   int64                  `protobuf:"varint,3,opt,name=max,proto3" json:"max,omitempty"`
+	// from the highest score, like RankTree.RevRangeByScore()
+	Reverse       bool `protobuf:"varint,4,opt,name=reverse,proto3" json:"reverse,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RangeByScoreRequest) Reset() {
+	*x = RangeByScoreRequest{}
+	mi := &file_rankservice_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RangeByScoreRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RangeByScoreRequest) ProtoMessage() {}
+
+func (x *RangeByScoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RangeByScoreRequest.ProtoReflect.Descriptor instead.
+func (*RangeByScoreRequest) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RangeByScoreRequest) GetBoard() string {
+	if x != nil {
+		return x.Board
+	}
+	return ""
+}
+
+func (x *RangeByScoreRequest) GetMin() int64 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *RangeByScoreRequest) GetMax() int64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *RangeByScoreRequest) GetReverse() bool {
+	if x != nil {
+		return x.Reverse
+	}
+	return false
+}
+
+// Ranks of the entries are in the order of the request.
+type RangeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*Entry               `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RangeResponse) Reset() {
+	*x = RangeResponse{}
+	mi := &file_rankservice_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RangeResponse) ProtoMessage() {}
+
+func (x *RangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RangeResponse.ProtoReflect.Descriptor instead.
+func (*RangeResponse) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RangeResponse) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type PopRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Board string                 `protobuf:"bytes,1,opt,name=board,proto3" json:"board,omitempty"`
+	// number of members, 1 if it is 0
+	Count int64 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	// pop the lowest scores instead of the highest
+	Min           bool `protobuf:"varint,3,opt,name=min,proto3" json:"min,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PopRequest) Reset() {
+	*x = PopRequest{}
+	mi := &file_rankservice_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PopRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PopRequest) ProtoMessage() {}
+
+func (x *PopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PopRequest.ProtoReflect.Descriptor instead.
+func (*PopRequest) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PopRequest) GetBoard() string {
+	if x != nil {
+		return x.Board
+	}
+	return ""
+}
+
+func (x *PopRequest) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *PopRequest) GetMin() bool {
+	if x != nil {
+		return x.Min
+	}
+	return false
+}
+
+// Ranks of the entries are their ranks before the pop, in the order of the request.
+type PopResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*Entry               `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PopResponse) Reset() {
+	*x = PopResponse{}
+	mi := &file_rankservice_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PopResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PopResponse) ProtoMessage() {}
+
+func (x *PopResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PopResponse.ProtoReflect.Descriptor instead.
+func (*PopResponse) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PopResponse) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type WatchTopRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Board         string                 `protobuf:"bytes,1,opt,name=board,proto3" json:"board,omitempty"`
+	K             int64                  `protobuf:"varint,2,opt,name=k,proto3" json:"k,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchTopRequest) Reset() {
+	*x = WatchTopRequest{}
+	mi := &file_rankservice_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchTopRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchTopRequest) ProtoMessage() {}
+
+func (x *WatchTopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchTopRequest.ProtoReflect.Descriptor instead.
+func (*WatchTopRequest) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *WatchTopRequest) GetBoard() string {
+	if x != nil {
+		return x.Board
+	}
+	return ""
+}
+
+func (x *WatchTopRequest) GetK() int64 {
+	if x != nil {
+		return x.K
+	}
+	return 0
+}
+
+// Ranks of the entries are from the highest score.
+type WatchTopResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*Entry               `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchTopResponse) Reset() {
+	*x = WatchTopResponse{}
+	mi := &file_rankservice_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchTopResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchTopResponse) ProtoMessage() {}
+
+func (x *WatchTopResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rankservice_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchTopResponse.ProtoReflect.Descriptor instead.
+func (*WatchTopResponse) Descriptor() ([]byte, []int) {
+	return file_rankservice_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WatchTopResponse) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+var File_rankservice_proto protoreflect.FileDescriptor
+
+const file_rankservice_proto_rawDesc = "" +
+	"\n" +
+	"\x11rankservice.proto\x12\vranktree.v1\"I\n" +
+	"\x05Entry\x12\x16\n" +
+	"\x06member\x18\x01 \x01(\tR\x06member\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x03R\x05score\x12\x12\n" +
+	"\x04rank\x18\x03 \x01(\x03R\x04rank\"P\n" +
+	"\n" +
+	"AddRequest\x12\x14\n" +
+	"\x05board\x18\x01 \x01(\tR\x05board\x12\x16\n" +
+	"\x06member\x18\x02 \x01(\tR\x06member\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\x03R\x05score\"#\n" +
+	"\vAddResponse\x12\x14\n" +
+	"\x05added\x18\x01 \x01(\bR\x05added\"`\n" +
+	"\x12IncrementByRequest\x12\x14\n" +
+	"\x05board\x18\x01 \x01(\tR\x05board\x12\x16\n" +
+	"\x06member\x18\x02 \x01(\tR\x06member\x12\x1c\n" +
+	"\tincrement\x18\x03 \x01(\x03R\tincrement\"+\n" +
+	"\x13IncrementByResponse\x12\x14\n" +
+	"\x05score\x18\x01 \x01(\x03R\x05score\"U\n" +
+	"\vRankRequest\x12\x14\n" +
+	"\x05board\x18\x01 \x01(\tR\x05board\x12\x16\n" +
+	"\x06member\x18\x02 \x01(\tR\x06member\x12\x18\n" +
+	"\areverse\x18\x03 \x01(\bR\areverse\"8\n" +
+	"\fRankResponse\x12\x12\n" +
+	"\x04rank\x18\x01 \x01(\x03R\x04rank\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x03R\x05score\"O\n" +
+	"\x0fRevRangeRequest\x12\x14\n" +
+	"\x05board\x18\x01 \x01(\tR\x05board\x12\x14\n" +
+	"\x05start\x18\x02 \x01(\x03R\x05start\x12\x10\n" +
+	"\x03end\x18\x03 \x01(\x03R\x03end\"i\n" +
+	"\x13RangeByScoreRequest\x12\x14\n" +
+	"\x05board\x18\x01 \x01(\tR\x05board\x12\x10\n" +
+	"\x03min\x18\x02 \x01(\x03R\x03min\x12\x10\n" +
+	"\x03max\x18\x03 \x01(\x03R\x03max\x12\x18\n" +
+	"\areverse\x18\x04 \x01(\bR\areverse\"=\n" +
+	"\rRangeResponse\x12,\n" +
+	"\aentries\x18\x01 \x03(\v2\x12.ranktree.v1.EntryR\aentries\"J\n" +
+	"\n" +
+	"PopRequest\x12\x14\n" +
+	"\x05board\x18\x01 \x01(\tR\x05board\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12\x10\n" +
+	"\x03min\x18\x03 \x01(\bR\x03min\";\n" +
+	"\vPopResponse\x12,\n" +
+	"\aentries\x18\x01 \x03(\v2\x12.ranktree.v1.EntryR\aentries\"5\n" +
+	"\x0fWatchTopRequest\x12\x14\n" +
+	"\x05board\x18\x01 \x01(\tR\x05board\x12\f\n" +
+	"\x01k\x18\x02 \x01(\x03R\x01k\"@\n" +
+	"\x10WatchTopResponse\x12,\n" +
+	"\aentries\x18\x01 \x03(\v2\x12.ranktree.v1.EntryR\aentries2\xef\x03\n" +
+	"\vRankService\x128\n" +
+	"\x03Add\x12\x17.ranktree.v1.AddRequest\x1a\x18.ranktree.v1.AddResponse\x12P\n" +
+	"\vIncrementBy\x12\x1f.ranktree.v1.IncrementByRequest\x1a .ranktree.v1.IncrementByResponse\x12;\n" +
+	"\x04Rank\x12\x18.ranktree.v1.RankRequest\x1a\x19.ranktree.v1.RankResponse\x12D\n" +
+	"\bRevRange\x12\x1c.ranktree.v1.RevRangeRequest\x1a\x1a.ranktree.v1.RangeResponse\x12L\n" +
+	"\fRangeByScore\x12 .ranktree.v1.RangeByScoreRequest\x1a\x1a.ranktree.v1.RangeResponse\x128\n" +
+	"\x03Pop\x12\x17.ranktree.v1.PopRequest\x1a\x18.ranktree.v1.PopResponse\x12I\n" +
+	"\bWatchTop\x12\x1c.ranktree.v1.WatchTopRequest\x1a\x1d.ranktree.v1.WatchTopResponse0\x01B)Z'github.com/ng1091/ranktree/ranktreegrpcb\x06proto3"
+
+var (
+	file_rankservice_proto_rawDescOnce sync.Once
+	file_rankservice_proto_rawDescData []byte
+)
+
+func file_rankservice_proto_rawDescGZIP() []byte {
+	file_rankservice_proto_rawDescOnce.Do(func() {
+		file_rankservice_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_rankservice_proto_rawDesc), len(file_rankservice_proto_rawDesc)))
+	})
+	return file_rankservice_proto_rawDescData
+}
+
+var file_rankservice_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_rankservice_proto_goTypes = []any{
+	(*Entry)(nil),               // 0: ranktree.v1.Entry
+	(*AddRequest)(nil),          // 1: ranktree.v1.AddRequest
+	(*AddResponse)(nil),         // 2: ranktree.v1.AddResponse
+	(*IncrementByRequest)(nil),  // 3: ranktree.v1.IncrementByRequest
+	(*IncrementByResponse)(nil), // 4: ranktree.v1.IncrementByResponse
+	(*RankRequest)(nil),         // 5: ranktree.v1.RankRequest
+	(*RankResponse)(nil),        // 6: ranktree.v1.RankResponse
+	(*RevRangeRequest)(nil),     // 7: ranktree.v1.RevRangeRequest
+	(*RangeByScoreRequest)(nil), // 8: ranktree.v1.RangeByScoreRequest
+	(*RangeResponse)(nil),       // 9: ranktree.v1.RangeResponse
+	(*PopRequest)(nil),          // 10: ranktree.v1.PopRequest
+	(*PopResponse)(nil),         // 11: ranktree.v1.PopResponse
+	(*WatchTopRequest)(nil),     // 12: ranktree.v1.WatchTopRequest
+	(*WatchTopResponse)(nil),    // 13: ranktree.v1.WatchTopResponse
+}
+var file_rankservice_proto_depIdxs = []int32{
+	0,  // 0: ranktree.v1.RangeResponse.entries:type_name -> ranktree.v1.Entry
+	0,  // 1: ranktree.v1.PopResponse.entries:type_name -> ranktree.v1.Entry
+	0,  // 2: ranktree.v1.WatchTopResponse.entries:type_name -> ranktree.v1.Entry
+	1,  // 3: ranktree.v1.RankService.Add:input_type -> ranktree.v1.AddRequest
+	3,  // 4: ranktree.v1.RankService.IncrementBy:input_type -> ranktree.v1.IncrementByRequest
+	5,  // 5: ranktree.v1.RankService.Rank:input_type -> ranktree.v1.RankRequest
+	7,  // 6: ranktree.v1.RankService.RevRange:input_type -> ranktree.v1.RevRangeRequest
+	8,  // 7: ranktree.v1.RankService.RangeByScore:input_type -> ranktree.v1.RangeByScoreRequest
+	10, // 8: ranktree.v1.RankService.Pop:input_type -> ranktree.v1.PopRequest
+	12, // 9: ranktree.v1.RankService.WatchTop:input_type -> ranktree.v1.WatchTopRequest
+	2,  // 10: ranktree.v1.RankService.Add:output_type -> ranktree.v1.AddResponse
+	4,  // 11: ranktree.v1.RankService.IncrementBy:output_type -> ranktree.v1.IncrementByResponse
+	6,  // 12: ranktree.v1.RankService.Rank:output_type -> ranktree.v1.RankResponse
+	9,  // 13: ranktree.v1.RankService.RevRange:output_type -> ranktree.v1.RangeResponse
+	9,  // 14: ranktree.v1.RankService.RangeByScore:output_type -> ranktree.v1.RangeResponse
+	11, // 15: ranktree.v1.RankService.Pop:output_type -> ranktree.v1.PopResponse
+	13, // 16: ranktree.v1.RankService.WatchTop:output_type -> ranktree.v1.WatchTopResponse
+	10, // [10:17] is the sub-list for method output_type
+	3,  // [3:10] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_rankservice_proto_init() }
+func file_rankservice_proto_init() {
+	if File_rankservice_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_rankservice_proto_rawDesc), len(file_rankservice_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_rankservice_proto_goTypes,
+		DependencyIndexes: file_rankservice_proto_depIdxs,
+		MessageInfos:      file_rankservice_proto_msgTypes,
+	}.Build()
+	File_rankservice_proto = out.File
+	file_rankservice_proto_goTypes = nil
+	file_rankservice_proto_depIdxs = nil
+}
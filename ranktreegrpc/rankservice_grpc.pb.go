@@ -0,0 +1,379 @@
+// RankService serves named RankTrees (boards).
+//
+// Ranks are 0-based like RankTree, a missing board or member is reported
+// as NOT_FOUND, a score out of the range of the board as OUT_OF_RANGE.
+//
+// Regenerate the Go code after changing this file:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	       --go-grpc_out=. --go-grpc_opt=paths=source_relative rankservice.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: rankservice.proto
+
+package ranktreegrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RankService_Add_FullMethodName          = "/ranktree.v1.RankService/Add"
+	RankService_IncrementBy_FullMethodName  = "/ranktree.v1.RankService/IncrementBy"
+	RankService_Rank_FullMethodName         = "/ranktree.v1.RankService/Rank"
+	RankService_RevRange_FullMethodName     = "/ranktree.v1.RankService/RevRange"
+	RankService_RangeByScore_FullMethodName = "/ranktree.v1.RankService/RangeByScore"
+	RankService_Pop_FullMethodName          = "/ranktree.v1.RankService/Pop"
+	RankService_WatchTop_FullMethodName     = "/ranktree.v1.RankService/WatchTop"
+)
+
+// RankServiceClient is the client API for RankService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RankServiceClient interface {
+	// Adds a member, see RankTree.Add().
+	Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*AddResponse, error)
+	// Increments the score of a member, it is added if it does not exist.
+	IncrementBy(ctx context.Context, in *IncrementByRequest, opts ...grpc.CallOption) (*IncrementByResponse, error)
+	// Returns the rank and the score of a member.
+	Rank(ctx context.Context, in *RankRequest, opts ...grpc.CallOption) (*RankResponse, error)
+	// Returns the members ranked [start, end] from the highest score, negative indexes count from the end.
+	RevRange(ctx context.Context, in *RevRangeRequest, opts ...grpc.CallOption) (*RangeResponse, error)
+	// Returns the members with scores in [min, max].
+	RangeByScore(ctx context.Context, in *RangeByScoreRequest, opts ...grpc.CallOption) (*RangeResponse, error)
+	// Removes and returns the members with the highest, or the lowest scores.
+	Pop(ctx context.Context, in *PopRequest, opts ...grpc.CallOption) (*PopResponse, error)
+	// Streams the top k members from the highest score, once on start and then after each change of them.
+	// Changes in quick succession may be coalesced into one message.
+	WatchTop(ctx context.Context, in *WatchTopRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchTopResponse], error)
+}
+
+type rankServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRankServiceClient(cc grpc.ClientConnInterface) RankServiceClient {
+	return &rankServiceClient{cc}
+}
+
+func (c *rankServiceClient) Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*AddResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddResponse)
+	err := c.cc.Invoke(ctx, RankService_Add_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankServiceClient) IncrementBy(ctx context.Context, in *IncrementByRequest, opts ...grpc.CallOption) (*IncrementByResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IncrementByResponse)
+	err := c.cc.Invoke(ctx, RankService_IncrementBy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankServiceClient) Rank(ctx context.Context, in *RankRequest, opts ...grpc.CallOption) (*RankResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RankResponse)
+	err := c.cc.Invoke(ctx, RankService_Rank_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankServiceClient) RevRange(ctx context.Context, in *RevRangeRequest, opts ...grpc.CallOption) (*RangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RangeResponse)
+	err := c.cc.Invoke(ctx, RankService_RevRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankServiceClient) RangeByScore(ctx context.Context, in *RangeByScoreRequest, opts ...grpc.CallOption) (*RangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RangeResponse)
+	err := c.cc.Invoke(ctx, RankService_RangeByScore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankServiceClient) Pop(ctx context.Context, in *PopRequest, opts ...grpc.CallOption) (*PopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PopResponse)
+	err := c.cc.Invoke(ctx, RankService_Pop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rankServiceClient) WatchTop(ctx context.Context, in *WatchTopRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchTopResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RankService_ServiceDesc.Streams[0], RankService_WatchTop_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchTopRequest, WatchTopResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RankService_WatchTopClient = grpc.ServerStreamingClient[WatchTopResponse]
+
+// RankServiceServer is the server API for RankService service.
+// All implementations must embed UnimplementedRankServiceServer
+// for forward compatibility.
+type RankServiceServer interface {
+	// Adds a member, see RankTree.Add().
+	Add(context.Context, *AddRequest) (*AddResponse, error)
+	// Increments the score of a member, it is added if it does not exist.
+	IncrementBy(context.Context, *IncrementByRequest) (*IncrementByResponse, error)
+	// Returns the rank and the score of a member.
+	Rank(context.Context, *RankRequest) (*RankResponse, error)
+	// Returns the members ranked [start, end] from the highest score, negative indexes count from the end.
+	RevRange(context.Context, *RevRangeRequest) (*RangeResponse, error)
+	// Returns the members with scores in [min, max].
+	RangeByScore(context.Context, *RangeByScoreRequest) (*RangeResponse, error)
+	// Removes and returns the members with the highest, or the lowest scores.
+	Pop(context.Context, *PopRequest) (*PopResponse, error)
+	// Streams the top k members from the highest score, once on start and then after each change of them.
+	// Changes in quick succession may be coalesced into one message.
+	WatchTop(*WatchTopRequest, grpc.ServerStreamingServer[WatchTopResponse]) error
+	mustEmbedUnimplementedRankServiceServer()
+}
+
+// UnimplementedRankServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRankServiceServer struct{}
+
+func (UnimplementedRankServiceServer) Add(context.Context, *AddRequest) (*AddResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Add not implemented")
+}
+func (UnimplementedRankServiceServer) IncrementBy(context.Context, *IncrementByRequest) (*IncrementByResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IncrementBy not implemented")
+}
+func (UnimplementedRankServiceServer) Rank(context.Context, *RankRequest) (*RankResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rank not implemented")
+}
+func (UnimplementedRankServiceServer) RevRange(context.Context, *RevRangeRequest) (*RangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevRange not implemented")
+}
+func (UnimplementedRankServiceServer) RangeByScore(context.Context, *RangeByScoreRequest) (*RangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RangeByScore not implemented")
+}
+func (UnimplementedRankServiceServer) Pop(context.Context, *PopRequest) (*PopResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Pop not implemented")
+}
+func (UnimplementedRankServiceServer) WatchTop(*WatchTopRequest, grpc.ServerStreamingServer[WatchTopResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchTop not implemented")
+}
+func (UnimplementedRankServiceServer) mustEmbedUnimplementedRankServiceServer() {}
+func (UnimplementedRankServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeRankServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RankServiceServer will
+// result in compilation errors.
+type UnsafeRankServiceServer interface {
+	mustEmbedUnimplementedRankServiceServer()
+}
+
+func RegisterRankServiceServer(s grpc.ServiceRegistrar, srv RankServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRankServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RankService_ServiceDesc, srv)
+}
+
+func _RankService_Add_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankServiceServer).Add(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankService_Add_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankServiceServer).Add(ctx, req.(*AddRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RankService_IncrementBy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrementByRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankServiceServer).IncrementBy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankService_IncrementBy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankServiceServer).IncrementBy(ctx, req.(*IncrementByRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RankService_Rank_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RankRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankServiceServer).Rank(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankService_Rank_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankServiceServer).Rank(ctx, req.(*RankRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RankService_RevRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankServiceServer).RevRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankService_RevRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankServiceServer).RevRange(ctx, req.(*RevRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RankService_RangeByScore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RangeByScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankServiceServer).RangeByScore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankService_RangeByScore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankServiceServer).RangeByScore(ctx, req.(*RangeByScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RankService_Pop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RankServiceServer).Pop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RankService_Pop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RankServiceServer).Pop(ctx, req.(*PopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RankService_WatchTop_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchTopRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RankServiceServer).WatchTop(m, &grpc.GenericServerStream[WatchTopRequest, WatchTopResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RankService_WatchTopServer = grpc.ServerStreamingServer[WatchTopResponse]
+
+// RankService_ServiceDesc is the grpc.ServiceDesc for RankService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RankService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ranktree.v1.RankService",
+	HandlerType: (*RankServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Add",
+			Handler:    _RankService_Add_Handler,
+		},
+		{
+			MethodName: "IncrementBy",
+			Handler:    _RankService_IncrementBy_Handler,
+		},
+		{
+			MethodName: "Rank",
+			Handler:    _RankService_Rank_Handler,
+		},
+		{
+			MethodName: "RevRange",
+			Handler:    _RankService_RevRange_Handler,
+		},
+		{
+			MethodName: "RangeByScore",
+			Handler:    _RankService_RangeByScore_Handler,
+		},
+		{
+			MethodName: "Pop",
+			Handler:    _RankService_Pop_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchTop",
+			Handler:       _RankService_WatchTop_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rankservice.proto",
+}
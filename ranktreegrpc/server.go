@@ -0,0 +1,304 @@
+// Package ranktreegrpc serves RankTrees over gRPC, see rankservice.proto.
+//
+// Register a Server to a grpc.Server:
+//
+//	s := grpc.NewServer()
+//	ranktreegrpc.RegisterRankServiceServer(s, ranktreegrpc.NewServer(newBoard))
+//
+// and call it by a Client:
+//
+//	client := ranktreegrpc.NewClient(conn)
+//	added, err := client.Add(ctx, "weekly", "Bob", 1234)
+package ranktreegrpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// Server implements RankServiceServer over named RankTrees (boards).
+// RankTrees are not safe for concurrent use, so calls are served one at a time.
+type Server struct {
+	UnimplementedRankServiceServer
+
+	mu			sync.Mutex										// held around the calls to the boards
+	boards		map[string]*ranktree.RankTree
+	newBoard	func(name string) (*ranktree.RankTree, error)	// creates a board on its first use
+}
+
+
+// NewServer creates a Server.
+// <newBoard> creates the RankTree of a board when it is used for the first time,
+// if it is nil, only the boards added by AddBoard() are served.
+func NewServer(newBoard func(name string) (*ranktree.RankTree, error)) *Server {
+	return &Server{
+		boards: make(map[string]*ranktree.RankTree),
+		newBoard: newBoard,
+	}
+}
+
+
+// AddBoard serves <tree> as the board <name>, replacing the board of the same name.
+// <tree> must not be used by others while it is served.
+func (s *Server) AddBoard(name string, tree *ranktree.RankTree) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.boards[name] = tree
+}
+
+
+// Returns the board <name>. If <create> is true, it is created if it does not exist.
+func (s *Server) board(name string, create bool) (*ranktree.RankTree, error) {
+	if tree, ok := s.boards[name]; ok {
+		return tree, nil
+	}
+
+	if create == false || s.newBoard == nil {
+		return nil, status.Errorf(codes.NotFound, "board %q does not exist", name)
+	}
+
+	tree, err := s.newBoard(name)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "create board %q: %v", name, err)
+	}
+	s.boards[name] = tree
+	return tree, nil
+}
+
+
+// Applies <op> to the board <name>, which is created if it does not exist.
+func (s *Server) apply(name string, op ranktree.Op) (ranktree.OpResult, error) {
+	if op.Member == "" {
+		return ranktree.OpResult{}, status.Error(codes.InvalidArgument, "member is empty")
+	}
+
+	tree, err := s.board(name, true)
+	if err != nil {
+		return ranktree.OpResult{}, err
+	}
+
+	results, err := tree.Apply([]ranktree.Op{op})
+	if errors.Is(err, ranktree.ErrOutOfRange) {
+		return ranktree.OpResult{}, status.Error(codes.OutOfRange, err.Error())
+	} else if err != nil {
+		return ranktree.OpResult{}, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return results[0], nil
+}
+
+
+func (s *Server) Add(ctx context.Context, req *AddRequest) (*AddResponse, error) {
+	score, err := toScore(req.Score)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.apply(req.Board, ranktree.Op{Type: ranktree.OpAdd, Member: req.Member, Score: score})
+	if status.Code(err) == codes.FailedPrecondition {
+		// the member exists
+		return &AddResponse{Added: false}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &AddResponse{Added: true}, nil
+}
+
+
+func (s *Server) IncrementBy(ctx context.Context, req *IncrementByRequest) (*IncrementByResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.apply(req.Board, ranktree.Op{Type: ranktree.OpIncrement, Member: req.Member, Score: toInt(req.Increment)})
+	if err != nil {
+		return nil, err
+	}
+	return &IncrementByResponse{Score: int64(result.Score)}, nil
+}
+
+
+func (s *Server) Rank(ctx context.Context, req *RankRequest) (*RankResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tree, err := s.board(req.Board, false)
+	if err != nil {
+		return nil, err
+	}
+
+	score := tree.Score(req.Member)
+	if score == -1 {
+		return nil, status.Errorf(codes.NotFound, "member %q does not exist", req.Member)
+	}
+
+	rank := tree.Rank(req.Member)
+	if req.Reverse {
+		rank = tree.RevRank(req.Member)
+	}
+	return &RankResponse{Rank: int64(rank), Score: int64(score)}, nil
+}
+
+
+func (s *Server) RevRange(ctx context.Context, req *RevRangeRequest) (*RangeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tree, err := s.board(req.Board, false)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := toInt(req.Start), toInt(req.End)
+	ranks := tree.RevRangeWithScore(start, end)
+
+	// rank of ranks[0], the same as RevRangeWithScore() sanitizes <start>
+	if start < 0 {
+		start = max(start + tree.Card(), 0)
+	}
+	return &RangeResponse{Entries: entries(ranks, start)}, nil
+}
+
+
+func (s *Server) RangeByScore(ctx context.Context, req *RangeByScoreRequest) (*RangeResponse, error) {
+	if req.Min > req.Max || req.Max < 0 {
+		return &RangeResponse{}, nil
+	}
+	low, high := toInt(max(req.Min, 0)), toInt(req.Max)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tree, err := s.board(req.Board, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// members before the range are those with a lower score, or a higher score if reversed
+	if req.Reverse {
+		first := tree.Card() - tree.Count(0, high)
+		return &RangeResponse{Entries: entries(tree.RevRangeByScore(low, high), first)}, nil
+	}
+	first := tree.Card() - tree.Count(low, math.MaxInt)
+	return &RangeResponse{Entries: entries(tree.RangeByScore(low, high), first)}, nil
+}
+
+
+func (s *Server) Pop(ctx context.Context, req *PopRequest) (*PopResponse, error) {
+	if req.Count < 0 {
+		return nil, status.Error(codes.InvalidArgument, "count is negative")
+	}
+	count := toInt(max(req.Count, 1))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tree, err := s.board(req.Board, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranks []ranktree.RankWithScore
+	if req.Min {
+		ranks = tree.PopMinN(count)
+	} else {
+		ranks = tree.PopMaxN(count)
+	}
+	return &PopResponse{Entries: entries(ranks, 0)}, nil
+}
+
+
+// WatchTop sends the top k members, then waits for changes of them until the stream is canceled.
+// Changes are detected by an observer of the board, which only signals the stream,
+// so a slow receiver does not block writers but gets the latest top k.
+func (s *Server) WatchTop(req *WatchTopRequest, stream RankService_WatchTopServer) error {
+	if req.K <= 0 {
+		return status.Error(codes.InvalidArgument, "k must be positive")
+	}
+	k := toInt(req.K)
+
+	s.mu.Lock()
+	tree, err := s.board(req.Board, true)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	changed := make(chan struct{}, 1)
+	changed <- struct{}{}
+	cancel := tree.OnChange(func(e ranktree.Event) {
+		// a member with a score lower than the k-th highest one is not in the top k, before or after the change.
+		// Scores are compared instead of ranks, as a member tied with the k-th score may move in or out of the top k
+		// without changing its score.
+		if low := tree.ScoreAtRevRank(k - 1); low == -1 || max(e.OldScore, e.NewScore) >= low {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	})
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		cancel()
+		s.mu.Unlock()
+	}()
+
+	var last []ranktree.RankWithScore
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-changed:
+		}
+
+		s.mu.Lock()
+		top := tree.RevRangeWithScore(0, k - 1)
+		s.mu.Unlock()
+
+		if last != nil && slices.Equal(top, last) {
+			continue
+		}
+		last = top
+
+		if err := stream.Send(&WatchTopResponse{Entries: entries(top, 0)}); err != nil {
+			return err
+		}
+	}
+}
+
+
+// Returns <score> as int, or an InvalidArgument error if it is negative.
+func toScore(score int64) (int, error) {
+	if score < 0 {
+		return 0, status.Error(codes.InvalidArgument, "score must be non-negative")
+	}
+	return toInt(score), nil
+}
+
+
+// Returns <n> as int, clamped to the range of int.
+func toInt(n int64) int {
+	return int(min(max(n, math.MinInt), math.MaxInt))
+}
+
+
+// Returns the entries of <ranks>, where ranks[0] is ranked <first>.
+func entries(ranks []ranktree.RankWithScore, first int) []*Entry {
+	entries := make([]*Entry, len(ranks))
+	for i, v := range ranks {
+		entries[i] = &Entry{Member: v.Member, Score: int64(v.Score), Rank: int64(first + i)}
+	}
+	return entries
+}
@@ -0,0 +1,265 @@
+package ranktreegrpc
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// Starts <srv> on an in-memory listener, returns a Client connected to it.
+func startServer(t *testing.T, srv *Server) *Client {
+	l := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	RegisterRankServiceServer(s, srv)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewClient(conn)
+}
+
+
+func newTestServer() *Server {
+	return NewServer(func(name string) (*ranktree.RankTree, error) {
+		return ranktree.New(0, 1000)
+	})
+}
+
+
+func checkCode(t *testing.T, name string, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("%s: err = %v, want %v", name, err, code)
+	}
+}
+
+
+func TestServer(t *testing.T) {
+	c := startServer(t, newTestServer())
+	ctx := context.Background()
+
+	for _, v := range []ranktree.RankWithScore{{Member: "alice", Score: 10}, {Member: "bob", Score: 30}, {Member: "charles", Score: 20}, {Member: "dave", Score: 40}, {Member: "erin", Score: 20}} {
+		if added, err := c.Add(ctx, "weekly", v.Member, v.Score); err != nil || added == false {
+			t.Fatalf("Add(%v) = %v, %v", v, added, err)
+		}
+	}
+	if added, err := c.Add(ctx, "weekly", "alice", 50); err != nil || added {
+		t.Errorf("Add(existing) = %v, %v", added, err)
+	}
+
+	if score, err := c.IncrementBy(ctx, "weekly", "alice", 25); err != nil || score != 35 {
+		t.Errorf("IncrementBy = %d, %v", score, err)
+	}
+	if score, err := c.IncrementBy(ctx, "weekly", "frank", 5); err != nil || score != 5 {
+		t.Errorf("IncrementBy(new member) = %d, %v", score, err)
+	}
+
+	// dave 40, alice 35, bob 30, charles 20, erin 20, frank 5
+	if rank, score, err := c.Rank(ctx, "weekly", "bob"); err != nil || rank != 3 || score != 30 {
+		t.Errorf("Rank = %d, %d, %v", rank, score, err)
+	}
+	if rank, score, err := c.RevRank(ctx, "weekly", "dave"); err != nil || rank != 0 || score != 40 {
+		t.Errorf("RevRank = %d, %d, %v", rank, score, err)
+	}
+
+	ranks, err := c.RevRange(ctx, "weekly", 0, 2)
+	if want := []ranktree.RankWithScore{{Member: "dave", Score: 40}, {Member: "alice", Score: 35}, {Member: "bob", Score: 30}}; err != nil || reflect.DeepEqual(ranks, want) == false {
+		t.Errorf("RevRange = %v, %v", ranks, err)
+	}
+
+	ranks, err = c.RangeByScore(ctx, "weekly", 20, 35, false)
+	if want := []ranktree.RankWithScore{{Member: "charles", Score: 20}, {Member: "erin", Score: 20}, {Member: "bob", Score: 30}, {Member: "alice", Score: 35}}; err != nil || reflect.DeepEqual(ranks, want) == false {
+		t.Errorf("RangeByScore = %v, %v", ranks, err)
+	}
+
+	ranks, err = c.RangeByScore(ctx, "weekly", -100, 100, true)
+	if err != nil || len(ranks) != 6 || ranks[0].Member != "dave" {
+		t.Errorf("RangeByScore(reverse) = %v, %v", ranks, err)
+	}
+
+	ranks, err = c.PopMax(ctx, "weekly", 1)
+	if want := []ranktree.RankWithScore{{Member: "dave", Score: 40}}; err != nil || reflect.DeepEqual(ranks, want) == false {
+		t.Errorf("PopMax = %v, %v", ranks, err)
+	}
+	ranks, err = c.PopMin(ctx, "weekly", 2)
+	if err != nil || len(ranks) != 2 || ranks[0] != (ranktree.RankWithScore{Member: "frank", Score: 5}) || ranks[1].Score != 20 {
+		t.Errorf("PopMin = %v, %v", ranks, err)
+	}
+}
+
+
+func TestServer_Ranks(t *testing.T) {
+	srv := newTestServer()
+	c := startServer(t, srv)
+	ctx := context.Background()
+
+	for i, member := range []string{"a", "b", "c", "d", "e"} {
+		c.Add(ctx, "weekly", member, (i + 1) * 10)
+	}
+
+	// ranks of the entries are in the order of the request
+	resp, err := srv.RevRange(ctx, &RevRangeRequest{Board: "weekly", Start: -2, End: -1})
+	if err != nil || len(resp.Entries) != 2 || resp.Entries[0].Rank != 3 || resp.Entries[0].Member != "b" {
+		t.Errorf("RevRange(-2, -1) = %v, %v", resp, err)
+	}
+
+	resp, err = srv.RangeByScore(ctx, &RangeByScoreRequest{Board: "weekly", Min: 25, Max: 45})
+	if err != nil || len(resp.Entries) != 2 || resp.Entries[0].Rank != 2 || resp.Entries[1].Rank != 3 {
+		t.Errorf("RangeByScore = %v, %v", resp, err)
+	}
+
+	resp, err = srv.RangeByScore(ctx, &RangeByScoreRequest{Board: "weekly", Min: 25, Max: 45, Reverse: true})
+	if err != nil || len(resp.Entries) != 2 || resp.Entries[0].Member != "d" || resp.Entries[0].Rank != 1 {
+		t.Errorf("RangeByScore(reverse) = %v, %v", resp, err)
+	}
+
+	resp, err = srv.RangeByScore(ctx, &RangeByScoreRequest{Board: "weekly", Min: 45, Max: 25})
+	if err != nil || len(resp.Entries) != 0 {
+		t.Errorf("RangeByScore(min > max) = %v, %v", resp, err)
+	}
+
+	// equal scores are ranked like RevRange() by RevRank() and RangeByScore(reverse)
+	c.Add(ctx, "weekly", "c2", 30)
+	c.Add(ctx, "weekly", "c1", 30)
+	ranks, err := c.RevRange(ctx, "weekly", 2, 4)
+	if want := []ranktree.RankWithScore{{Member: "c", Score: 30}, {Member: "c1", Score: 30}, {Member: "c2", Score: 30}}; err != nil || reflect.DeepEqual(ranks, want) == false {
+		t.Errorf("RevRange(2, 4) = %v, %v", ranks, err)
+	}
+	resp, err = srv.RangeByScore(ctx, &RangeByScoreRequest{Board: "weekly", Min: 30, Max: 30, Reverse: true})
+	for i, e := range resp.GetEntries() {
+		if rank, _, err := c.RevRank(ctx, "weekly", e.Member); err != nil || rank != i + 2 || e.Rank != int64(rank) || e.Member != ranks[i].Member {
+			t.Errorf("RevRank(%q) = %d, %v, entry %v", e.Member, rank, err, e)
+		}
+	}
+	if err != nil || len(resp.GetEntries()) != 3 {
+		t.Errorf("RangeByScore(30, 30, reverse) = %v, %v", resp, err)
+	}
+	if rank, _, err := c.Rank(ctx, "weekly", "c1"); err != nil || rank != 3 {
+		t.Errorf("Rank(\"c1\") = %d, %v", rank, err)
+	}
+}
+
+
+func TestServer_Errors(t *testing.T) {
+	c := startServer(t, newTestServer())
+	ctx := context.Background()
+	c.Add(ctx, "weekly", "alice", 10)
+
+	_, err := c.Add(ctx, "weekly", "bob", 2000)
+	checkCode(t, "Add(out of range)", err, codes.OutOfRange)
+	_, err = c.Add(ctx, "weekly", "bob", -1)
+	checkCode(t, "Add(negative)", err, codes.InvalidArgument)
+	_, err = c.Add(ctx, "weekly", "", 1)
+	checkCode(t, "Add(empty member)", err, codes.InvalidArgument)
+	_, err = c.IncrementBy(ctx, "weekly", "alice", -20)
+	checkCode(t, "IncrementBy(out of range)", err, codes.OutOfRange)
+
+	_, _, err = c.Rank(ctx, "weekly", "bob")
+	checkCode(t, "Rank(missing member)", err, codes.NotFound)
+	_, _, err = c.Rank(ctx, "monthly", "alice")
+	checkCode(t, "Rank(missing board)", err, codes.NotFound)
+	_, err = c.RevRange(ctx, "monthly", 0, -1)
+	checkCode(t, "RevRange(missing board)", err, codes.NotFound)
+	_, err = c.PopMax(ctx, "monthly", 1)
+	checkCode(t, "PopMax(missing board)", err, codes.NotFound)
+
+	err = c.WatchTop(ctx, "weekly", 0, func([]ranktree.RankWithScore) {})
+	checkCode(t, "WatchTop(k = 0)", err, codes.InvalidArgument)
+
+	// failed calls change nothing
+	if ranks, err := c.RevRange(ctx, "weekly", 0, -1); err != nil || reflect.DeepEqual(ranks, []ranktree.RankWithScore{{Member: "alice", Score: 10}}) == false {
+		t.Errorf("RevRange = %v, %v", ranks, err)
+	}
+
+	// without newBoard, boards must be added
+	tree, _ := ranktree.New(0, 100)
+	srv := NewServer(nil)
+	srv.AddBoard("weekly", tree)
+	c = startServer(t, srv)
+
+	_, err = c.Add(ctx, "monthly", "alice", 10)
+	checkCode(t, "Add(missing board)", err, codes.NotFound)
+	if added, err := c.Add(ctx, "weekly", "alice", 10); err != nil || added == false || tree.Score("alice") != 10 {
+		t.Errorf("Add(added board) = %v, %v", added, err)
+	}
+}
+
+
+func TestServer_WatchTop(t *testing.T) {
+	c := startServer(t, newTestServer())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c.Add(ctx, "weekly", "alice", 10)
+
+	updates := make(chan []ranktree.RankWithScore, 16)
+	done := make(chan error)
+	go func() {
+		done <- c.WatchTop(ctx, "weekly", 2, func(top []ranktree.RankWithScore) {
+			updates <- top
+		})
+	}()
+
+	next := func() []ranktree.RankWithScore {
+		t.Helper()
+		select {
+		case top := <-updates:
+			return top
+		case <-time.After(5 * time.Second):
+			t.Fatal("no update of WatchTop")
+			return nil
+		}
+	}
+
+	if top := next(); reflect.DeepEqual(top, []ranktree.RankWithScore{{Member: "alice", Score: 10}}) == false {
+		t.Fatalf("initial top = %v", top)
+	}
+
+	c.Add(ctx, "weekly", "bob", 20)
+	if top := next(); reflect.DeepEqual(top, []ranktree.RankWithScore{{Member: "bob", Score: 20}, {Member: "alice", Score: 10}}) == false {
+		t.Fatalf("top = %v", top)
+	}
+
+	// changes below the top 2 are not sent, the next update is of dave
+	c.Add(ctx, "weekly", "charles", 5)
+	c.IncrementBy(ctx, "weekly", "charles", 1)
+	c.Add(ctx, "weekly", "dave", 30)
+	if top := next(); reflect.DeepEqual(top, []ranktree.RankWithScore{{Member: "dave", Score: 30}, {Member: "bob", Score: 20}}) == false {
+		t.Errorf("top = %v", top)
+	}
+
+	// a member of the top 2 leaves
+	c.PopMax(ctx, "weekly", 1)
+	if top := next(); reflect.DeepEqual(top, []ranktree.RankWithScore{{Member: "bob", Score: 20}, {Member: "alice", Score: 10}}) == false {
+		t.Errorf("top = %v", top)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("WatchTop = %v after cancel", err)
+	}
+	select {
+	case top := <-updates:
+		t.Errorf("unexpected update %v", top)
+	default:
+	}
+}
@@ -0,0 +1,438 @@
+// Package ranktreehttp exposes RankTrees as leaderboards over HTTP/JSON.
+//
+// Routes:
+//
+//	POST /boards/{name}/scores              sets or increments scores
+//	GET  /boards/{name}/top?n=&offset=      a page of the leaderboard, from the highest score
+//	GET  /boards/{name}/members/{id}/rank   the rank of a member
+//	GET  /boards/{name}/around/{id}?before=&after=
+//	                                        a member and its neighbors
+//
+// Ranks are 1-based, the member with the highest score is ranked 1.
+// Errors are replied as {"error": {"code": ..., "message": ...}}.
+package ranktreehttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// Limits of a request.
+const (
+	defaultPageSize	= 10
+	maxPageSize		= 100
+	defaultAround	= 5
+	maxAround		= 50
+	maxUpdates		= 1000
+	maxNameLen		= 128
+	maxMemberLen	= 256
+	maxBodySize		= 1 << 20
+)
+
+
+// Entry is a member of a leaderboard.
+type Entry struct {
+	Rank	int		`json:"rank"`
+	Member	string	`json:"member"`
+	Score	int		`json:"score"`
+}
+
+
+// ScoreUpdate is an element of the body of POST /boards/{name}/scores.
+// The score of the member is set to Score, or incremented by Score if Increment is true.
+// A member which does not exist is added.
+type ScoreUpdate struct {
+	Member		string	`json:"member"`
+	Score		int		`json:"score"`
+	Increment	bool	`json:"increment,omitempty"`
+}
+
+
+// Page is the reply of GET /boards/{name}/top and GET /boards/{name}/around/{id}.
+// NextOffset is the offset of the next page of /top, nil if it is the last page.
+type Page struct {
+	Board		string	`json:"board"`
+	Total		int		`json:"total"`
+	Offset		int		`json:"offset"`
+	Entries		[]Entry	`json:"entries"`
+	NextOffset	*int	`json:"next_offset,omitempty"`
+}
+
+
+// Error is the body of an error reply.
+type Error struct {
+	Code	string	`json:"code"`
+	Message	string	`json:"message"`
+}
+
+
+// Handler serves leaderboards backed by RankTrees.
+type Handler struct {
+	mu			sync.Mutex										// held around the calls to the boards
+	boards		map[string]*ranktree.RankTree
+	newBoard	func(name string) (*ranktree.RankTree, error)	// creates a board on its first score
+}
+
+
+// handlerFunc serves a request to the board <name>, <member> is empty for the routes without it.
+type handlerFunc func(w http.ResponseWriter, r *http.Request, name, member string)
+
+
+// NewHandler creates a Handler.
+// <newBoard> creates the RankTree of a board when a score is posted to it for the first time,
+// if it is nil, only the boards added by AddBoard() are served.
+func NewHandler(newBoard func(name string) (*ranktree.RankTree, error)) *Handler {
+	return &Handler{
+		boards: make(map[string]*ranktree.RankTree),
+		newBoard: newBoard,
+	}
+}
+
+
+// AddBoard serves <tree> as the board <name>, replacing the board of the same name.
+// <tree> must not be used by others while it is served.
+func (h *Handler) AddBoard(name string, tree *ranktree.RankTree) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.boards[name] = tree
+}
+
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method, fn, name, member := h.route(r.URL)
+	if fn == nil {
+		writeError(w, http.StatusNotFound, "not_found", "no such route")
+		return
+	}
+
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method + " is not allowed")
+		return
+	}
+
+	if len(name) > maxNameLen {
+		writeError(w, http.StatusBadRequest, "invalid_argument", "board name is too long")
+		return
+	}
+
+	fn(w, r, name, member)
+}
+
+
+// Returns the method and the handler of <u>, with the board name and the member in its path.
+// The handler is nil if no route matches. Path segments are unescaped, so a member may contain "/".
+func (h *Handler) route(u *url.URL) (method string, fn handlerFunc, name, member string) {
+	segments := strings.Split(strings.TrimPrefix(u.EscapedPath(), "/"), "/")
+	for i, seg := range segments {
+		var err error
+		if segments[i], err = url.PathUnescape(seg); err != nil || segments[i] == "" {
+			return "", nil, "", ""
+		}
+	}
+
+	if len(segments) < 3 || segments[0] != "boards" {
+		return "", nil, "", ""
+	}
+	name = segments[1]
+
+	switch {
+	case len(segments) == 3 && segments[2] == "scores":
+		return http.MethodPost, h.postScores, name, ""
+	case len(segments) == 3 && segments[2] == "top":
+		return http.MethodGet, h.top, name, ""
+	case len(segments) == 5 && segments[2] == "members" && segments[4] == "rank":
+		return http.MethodGet, h.rank, name, segments[3]
+	case len(segments) == 4 && segments[2] == "around":
+		return http.MethodGet, h.around, name, segments[3]
+	}
+	return "", nil, "", ""
+}
+
+
+// Calls <fn> with the board <name> under the lock, or writes a not found error.
+// Returns false if the board does not exist.
+func (h *Handler) withBoard(w http.ResponseWriter, name string, fn func(tree *ranktree.RankTree)) bool {
+	h.mu.Lock()
+	tree, ok := h.boards[name]
+	if ok {
+		fn(tree)
+	}
+	h.mu.Unlock()
+
+	if ok == false {
+		writeBoardNotFound(w, name)
+	}
+	return ok
+}
+
+
+// POST /boards/{name}/scores, the body is a ScoreUpdate or an array of them.
+// The updates are applied atomically, the reply is the entries of the updated members.
+func (h *Handler) postScores(w http.ResponseWriter, r *http.Request, name, _ string) {
+	updates, err := decodeUpdates(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_argument", err.Error())
+		return
+	}
+
+	ops := make([]ranktree.Op, len(updates))
+	for i, u := range updates {
+		if err := validateMember(u.Member); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_argument", err.Error())
+			return
+		}
+
+		if u.Increment {
+			ops[i] = ranktree.Op{Type: ranktree.OpIncrement, Member: u.Member, Score: u.Score}
+		} else if u.Score < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_argument", "score must be non-negative")
+			return
+		} else {
+			ops[i] = ranktree.Op{Type: ranktree.OpUpdate, Member: u.Member, Score: u.Score, Insert: true}
+		}
+	}
+
+	entries, status, e := h.apply(name, updates, ops)
+	if e != nil {
+		writeError(w, status, e.Code, e.Message)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]Entry{"entries": entries})
+}
+
+
+// Applies <ops> of <updates> to the board <name> under the lock, the board is created if it does not exist.
+// Returns the entries of the updated members, or the status and the error to reply.
+func (h *Handler) apply(name string, updates []ScoreUpdate, ops []ranktree.Op) ([]Entry, int, *Error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	tree, ok := h.boards[name]
+	if ok == false {
+		if h.newBoard == nil {
+			return nil, http.StatusNotFound, boardNotFound(name)
+		}
+		var err error
+		if tree, err = h.newBoard(name); err != nil {
+			return nil, http.StatusInternalServerError, &Error{"internal", err.Error()}
+		}
+	}
+
+	if _, err := tree.Apply(ops); err != nil {
+		if errors.Is(err, ranktree.ErrOutOfRange) {
+			return nil, http.StatusUnprocessableEntity, &Error{"out_of_range", err.Error()}
+		}
+		return nil, http.StatusUnprocessableEntity, &Error{"rejected", err.Error()}
+	}
+	h.boards[name] = tree
+
+	entries := make([]Entry, len(updates))
+	for i, u := range updates {
+		entries[i] = Entry{tree.RevRank(u.Member) + 1, u.Member, tree.Score(u.Member)}
+	}
+	return entries, http.StatusOK, nil
+}
+
+
+// Decodes a ScoreUpdate or an array of them.
+func decodeUpdates(r io.Reader) ([]ScoreUpdate, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid body: %v", err)
+	}
+
+	var updates []ScoreUpdate
+	var err error
+	if len(raw) > 0 && raw[0] == '[' {
+		err = strictUnmarshal(raw, &updates)
+	} else {
+		updates = make([]ScoreUpdate, 1)
+		err = strictUnmarshal(raw, &updates[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid body: %v", err)
+	}
+
+	if len(updates) == 0 {
+		return nil, errors.New("no scores")
+	}
+	if len(updates) > maxUpdates {
+		return nil, fmt.Errorf("too many scores, at most %d", maxUpdates)
+	}
+	return updates, nil
+}
+
+
+// Unmarshals <data> into <v>, unknown fields are rejected.
+func strictUnmarshal(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+
+func validateMember(member string) error {
+	if member == "" {
+		return errors.New("member is empty")
+	}
+	if len(member) > maxMemberLen {
+		return errors.New("member is too long")
+	}
+	return nil
+}
+
+
+// GET /boards/{name}/top?n=&offset=
+func (h *Handler) top(w http.ResponseWriter, r *http.Request, name, _ string) {
+	n, ok := queryInt(w, r, "n", defaultPageSize, 1, maxPageSize)
+	if ok == false {
+		return
+	}
+	offset, ok := queryInt(w, r, "offset", 0, 0, -1)
+	if ok == false {
+		return
+	}
+
+	page := Page{Board: name, Offset: offset, Entries: []Entry{}}
+	var ranks []ranktree.RankWithScore
+	found := h.withBoard(w, name, func(tree *ranktree.RankTree) {
+		page.Total = tree.Card()
+		if offset < page.Total {
+			ranks = tree.RevRangeWithScore(offset, offset + n - 1)
+		}
+	})
+	if found == false {
+		return
+	}
+
+	if len(ranks) > 0 {
+		page.Entries = entries(ranks, offset)
+		if next := offset + len(ranks); next < page.Total {
+			page.NextOffset = &next
+		}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+
+// GET /boards/{name}/members/{id}/rank
+func (h *Handler) rank(w http.ResponseWriter, r *http.Request, name, member string) {
+	reply := struct {
+		Entry
+		Total	int		`json:"total"`
+	}{Entry: Entry{Member: member}}
+	found := h.withBoard(w, name, func(tree *ranktree.RankTree) {
+		reply.Rank = tree.RevRank(member) + 1
+		reply.Score = tree.Score(member)
+		reply.Total = tree.Card()
+	})
+	if found == false {
+		return
+	}
+
+	if reply.Rank == 0 {
+		writeMemberNotFound(w, member)
+		return
+	}
+	writeJSON(w, http.StatusOK, reply)
+}
+
+
+// GET /boards/{name}/around/{id}?before=&after=
+func (h *Handler) around(w http.ResponseWriter, r *http.Request, name, member string) {
+	before, ok := queryInt(w, r, "before", defaultAround, 0, maxAround)
+	if ok == false {
+		return
+	}
+	after, ok := queryInt(w, r, "after", defaultAround, 0, maxAround)
+	if ok == false {
+		return
+	}
+
+	var ranks []ranktree.RankWithScore
+	first, total := -1, 0
+	found := h.withBoard(w, name, func(tree *ranktree.RankTree) {
+		ranks, first = tree.Around(member, before, after, true)
+		total = tree.Card()
+	})
+	if found == false {
+		return
+	}
+
+	if first == -1 {
+		writeMemberNotFound(w, member)
+		return
+	}
+	writeJSON(w, http.StatusOK, Page{Board: name, Total: total, Offset: first, Entries: entries(ranks, first)})
+}
+
+
+// Returns the entries of <ranks>, where ranks[0] is at the 0-based <offset>.
+func entries(ranks []ranktree.RankWithScore, offset int) []Entry {
+	entries := make([]Entry, len(ranks))
+	for i, v := range ranks {
+		entries[i] = Entry{offset + i + 1, v.Member, v.Score}
+	}
+	return entries
+}
+
+
+// Returns the query parameter <key> as an integer in [min, max], or <def> if it is absent.
+// A negative <max> means no upper bound. Writes an error and returns false if it is invalid.
+func queryInt(w http.ResponseWriter, r *http.Request, key string, def, min, max int) (int, bool) {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return def, true
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || (max >= 0 && n > max) {
+		msg := fmt.Sprintf("%s must be an integer >= %d", key, min)
+		if max >= 0 {
+			msg = fmt.Sprintf("%s must be an integer in [%d, %d]", key, min, max)
+		}
+		writeError(w, http.StatusBadRequest, "invalid_argument", msg)
+		return 0, false
+	}
+	return n, true
+}
+
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]Error{"error": {code, message}})
+}
+
+
+func boardNotFound(name string) *Error {
+	return &Error{"board_not_found", fmt.Sprintf("board %q does not exist", name)}
+}
+
+
+func writeBoardNotFound(w http.ResponseWriter, name string) {
+	e := boardNotFound(name)
+	writeError(w, http.StatusNotFound, e.Code, e.Message)
+}
+
+
+func writeMemberNotFound(w http.ResponseWriter, member string) {
+	writeError(w, http.StatusNotFound, "member_not_found", fmt.Sprintf("member %q does not exist", member))
+}
@@ -0,0 +1,269 @@
+package ranktreehttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+func newTestHandler() *Handler {
+	return NewHandler(func(name string) (*ranktree.RankTree, error) {
+		return ranktree.New(0, 1000)
+	})
+}
+
+
+// Sends a request to <h>, decodes the JSON reply into <v> if it is not nil, returns the status code.
+func do(t *testing.T, h http.Handler, method, target, body string, v any) int {
+	t.Helper()
+
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s: Content-Type = %q", method, target, ct)
+	}
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: %v, body %q", method, target, err, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+
+// Sends a request which should fail, checks the status and the error code.
+func checkError(t *testing.T, h http.Handler, method, target, body string, status int, code string) {
+	t.Helper()
+
+	var reply map[string]Error
+	if got := do(t, h, method, target, body, &reply); got != status {
+		t.Errorf("%s %s: status = %d, want %d", method, target, got, status)
+	}
+	if reply["error"].Code != code || reply["error"].Message == "" {
+		t.Errorf("%s %s: error = %+v, want code %q", method, target, reply["error"], code)
+	}
+}
+
+
+func TestHandler(t *testing.T) {
+	h := newTestHandler()
+
+	var posted map[string][]Entry
+	body := `[{"member": "alice", "score": 10}, {"member": "bob", "score": 30}, {"member": "charles", "score": 20},
+		{"member": "dave", "score": 40}, {"member": "erin", "score": 5}]`
+	if status := do(t, h, "POST", "/boards/weekly/scores", body, &posted); status != http.StatusOK {
+		t.Fatalf("POST status = %d", status)
+	}
+	want := []Entry{{4, "alice", 10}, {2, "bob", 30}, {3, "charles", 20}, {1, "dave", 40}, {5, "erin", 5}}
+	if reflect.DeepEqual(posted["entries"], want) == false {
+		t.Errorf("POST entries = %v, want %v", posted["entries"], want)
+	}
+
+	// a single update, incremented
+	if do(t, h, "POST", "/boards/weekly/scores", `{"member": "alice", "score": 25, "increment": true}`, &posted); reflect.DeepEqual(posted["entries"], []Entry{{2, "alice", 35}}) == false {
+		t.Errorf("POST increment entries = %v", posted["entries"])
+	}
+
+	// dave 40, alice 35, bob 30, charles 20, erin 5
+	var page Page
+	if status := do(t, h, "GET", "/boards/weekly/top?n=2", "", &page); status != http.StatusOK {
+		t.Fatalf("GET top status = %d", status)
+	}
+	if page.Total != 5 || page.Offset != 0 || page.NextOffset == nil || *page.NextOffset != 2 ||
+		reflect.DeepEqual(page.Entries, []Entry{{1, "dave", 40}, {2, "alice", 35}}) == false {
+		t.Errorf("GET top?n=2 = %+v", page)
+	}
+
+	page = Page{}
+	do(t, h, "GET", "/boards/weekly/top?n=2&offset=4", "", &page)
+	if page.Offset != 4 || page.NextOffset != nil || reflect.DeepEqual(page.Entries, []Entry{{5, "erin", 5}}) == false {
+		t.Errorf("GET top?offset=4 = %+v", page)
+	}
+
+	page = Page{}
+	do(t, h, "GET", "/boards/weekly/top?offset=10", "", &page)
+	if page.Total != 5 || page.Entries == nil || len(page.Entries) != 0 || page.NextOffset != nil {
+		t.Errorf("GET top?offset=10 = %+v", page)
+	}
+
+	var rank struct {
+		Entry
+		Total	int
+	}
+	if status := do(t, h, "GET", "/boards/weekly/members/bob/rank", "", &rank); status != http.StatusOK {
+		t.Fatalf("GET rank status = %d", status)
+	}
+	if rank.Entry != (Entry{3, "bob", 30}) || rank.Total != 5 {
+		t.Errorf("GET rank = %+v", rank)
+	}
+
+	page = Page{}
+	do(t, h, "GET", "/boards/weekly/around/bob?before=1&after=5", "", &page)
+	if page.Offset != 1 || reflect.DeepEqual(page.Entries, []Entry{{2, "alice", 35}, {3, "bob", 30}, {4, "charles", 20}, {5, "erin", 5}}) == false {
+		t.Errorf("GET around = %+v", page)
+	}
+
+	// member names are escaped in the path
+	do(t, h, "POST", "/boards/weekly/scores", `{"member": "frank smith/2", "score": 50}`, nil)
+	do(t, h, "GET", "/boards/weekly/members/frank%20smith%2F2/rank", "", &rank)
+	if rank.Entry != (Entry{1, "frank smith/2", 50}) {
+		t.Errorf("GET rank of an escaped member = %+v", rank)
+	}
+}
+
+
+func TestHandler_Ties(t *testing.T) {
+	h := newTestHandler()
+
+	// equal scores are ranked in lexicographical order by every route
+	var posted map[string][]Entry
+	body := `[{"member": "c", "score": 20}, {"member": "a", "score": 20}, {"member": "d", "score": 30}, {"member": "b", "score": 20}]`
+	do(t, h, "POST", "/boards/weekly/scores", body, &posted)
+	want := []Entry{{4, "c", 20}, {2, "a", 20}, {1, "d", 30}, {3, "b", 20}}
+	if reflect.DeepEqual(posted["entries"], want) == false {
+		t.Errorf("POST entries = %v, want %v", posted["entries"], want)
+	}
+
+	var page Page
+	do(t, h, "GET", "/boards/weekly/top", "", &page)
+	want = []Entry{{1, "d", 30}, {2, "a", 20}, {3, "b", 20}, {4, "c", 20}}
+	if reflect.DeepEqual(page.Entries, want) == false {
+		t.Errorf("GET top = %v, want %v", page.Entries, want)
+	}
+
+	for _, e := range want {
+		var rank struct {
+			Entry
+			Total	int
+		}
+		do(t, h, "GET", "/boards/weekly/members/" + e.Member + "/rank", "", &rank)
+		if rank.Entry != e {
+			t.Errorf("GET rank = %+v, want %+v", rank.Entry, e)
+		}
+
+		page = Page{}
+		do(t, h, "GET", "/boards/weekly/around/" + e.Member + "?before=0&after=0", "", &page)
+		if reflect.DeepEqual(page.Entries, []Entry{e}) == false {
+			t.Errorf("GET around = %v, want %v", page.Entries, e)
+		}
+	}
+}
+
+
+func TestHandler_Errors(t *testing.T) {
+	h := newTestHandler()
+	do(t, h, "POST", "/boards/weekly/scores", `{"member": "alice", "score": 10}`, nil)
+
+	tests := []struct {
+		method, target, body	string
+		status					int
+		code					string
+	}{
+		{"GET", "/boards/weekly", "", http.StatusNotFound, "not_found"},
+		{"GET", "/boards/weekly/scores", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"POST", "/boards/weekly/top", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"GET", "/boards/monthly/top", "", http.StatusNotFound, "board_not_found"},
+		{"GET", "/boards/weekly/members/bob/rank", "", http.StatusNotFound, "member_not_found"},
+		{"GET", "/boards/weekly/around/bob", "", http.StatusNotFound, "member_not_found"},
+		{"GET", "/boards/weekly/top?n=0", "", http.StatusBadRequest, "invalid_argument"},
+		{"GET", "/boards/weekly/top?n=101", "", http.StatusBadRequest, "invalid_argument"},
+		{"GET", "/boards/weekly/top?offset=-1", "", http.StatusBadRequest, "invalid_argument"},
+		{"GET", "/boards/weekly/around/alice?before=x", "", http.StatusBadRequest, "invalid_argument"},
+		{"GET", "/boards/" + strings.Repeat("x", maxNameLen + 1) + "/top", "", http.StatusBadRequest, "invalid_argument"},
+		{"POST", "/boards/weekly/scores", `not json`, http.StatusBadRequest, "invalid_argument"},
+		{"POST", "/boards/weekly/scores", `[]`, http.StatusBadRequest, "invalid_argument"},
+		{"POST", "/boards/weekly/scores", `{"member": "bob", "points": 1}`, http.StatusBadRequest, "invalid_argument"},
+		{"POST", "/boards/weekly/scores", `{"member": "", "score": 1}`, http.StatusBadRequest, "invalid_argument"},
+		{"POST", "/boards/weekly/scores", `{"member": "bob", "score": -1}`, http.StatusBadRequest, "invalid_argument"},
+		{"POST", "/boards/weekly/scores", `[{"member": "bob", "score": 1}, {"member": "alice", "score": 2000}]`, http.StatusUnprocessableEntity, "out_of_range"},
+		{"POST", "/boards/weekly/scores", `{"member": "alice", "score": -20, "increment": true}`, http.StatusUnprocessableEntity, "out_of_range"},
+	}
+
+	for _, test := range tests {
+		checkError(t, h, test.method, test.target, test.body, test.status, test.code)
+	}
+
+	// failed updates change nothing
+	var page Page
+	do(t, h, "GET", "/boards/weekly/top", "", &page)
+	if reflect.DeepEqual(page.Entries, []Entry{{1, "alice", 10}}) == false {
+		t.Errorf("GET top after failed updates = %+v", page)
+	}
+
+	// a board is not created by a failed update
+	checkError(t, h, "POST", "/boards/monthly/scores", `{"member": "bob", "score": 2000}`, http.StatusUnprocessableEntity, "out_of_range")
+	checkError(t, h, "GET", "/boards/monthly/top", "", http.StatusNotFound, "board_not_found")
+}
+
+
+func TestHandler_AddBoard(t *testing.T) {
+	tree, err := ranktree.New(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Add("alice", 10)
+
+	h := NewHandler(nil)
+	h.AddBoard("weekly", tree)
+
+	var page Page
+	do(t, h, "GET", "/boards/weekly/top", "", &page)
+	if reflect.DeepEqual(page.Entries, []Entry{{1, "alice", 10}}) == false {
+		t.Errorf("GET top = %+v", page)
+	}
+
+	do(t, h, "POST", "/boards/weekly/scores", `{"member": "bob", "score": 20}`, nil)
+	if tree.Score("bob") != 20 {
+		t.Error("POST does not update the added board")
+	}
+
+	// boards are not created without newBoard
+	checkError(t, h, "POST", "/boards/monthly/scores", `{"member": "bob", "score": 20}`, http.StatusNotFound, "board_not_found")
+}
+
+
+func TestHandler_Concurrent(t *testing.T) {
+	srv := httptest.NewServer(newTestHandler())
+	defer srv.Close()
+
+	done := make(chan bool)
+	for i := 0; i < 8; i++ {
+		go func() {
+			defer func() { done <- true }()
+			for j := 0; j < 50; j++ {
+				resp, err := http.Post(srv.URL + "/boards/weekly/scores", "application/json",
+					strings.NewReader(`{"member": "alice", "score": 1, "increment": true}`))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp.Body.Close()
+			}
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+
+	resp, err := http.Get(srv.URL + "/boards/weekly/members/alice/rank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var entry Entry
+	if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Score != 400 {
+		t.Errorf("score after concurrent increments = %d, want 400", entry.Score)
+	}
+}
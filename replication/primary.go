@@ -0,0 +1,194 @@
+package replication
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// Primary records the changes of a RankTree and streams them to replicas.
+// The RankTree must be accessed by Update() and View() only.
+type Primary struct {
+	mu		sync.Mutex
+	cond	*sync.Cond				// broadcasts new ops and Close()
+	tree	*ranktree.RankTree
+	id		string					// changes when the Primary is recreated, so replicas of another one do a full sync
+	seq		uint64					// sequence number of the last op
+	backlog	[]op					// ring of the latest ops, op <seq> is at (seq - 1) % len(backlog)
+	closed	bool
+	cancel	func()
+}
+
+
+// NewPrimary creates a Primary replicating <tree>, which keeps the latest <backlog> ops
+// for replicas to resume from. Replicas which fall further behind do a full sync.
+func NewPrimary(tree *ranktree.RankTree, backlog int) *Primary {
+	if backlog < 1 {
+		backlog = 1
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	p := &Primary{
+		tree:		tree,
+		id:			hex.EncodeToString(id),
+		backlog:	make([]op, backlog),
+	}
+	p.cond = sync.NewCond(&p.mu)
+
+	// called by the modifications in Update(), which hold the lock
+	p.cancel = tree.OnChange(func(e ranktree.Event) {
+		p.seq++
+		p.backlog[(p.seq - 1) % uint64(len(p.backlog))] = op{seq: p.seq, member: e.Member, score: e.NewScore}
+		p.cond.Broadcast()
+	})
+	return p
+}
+
+
+// Update calls <fn> to modify the RankTree, the changes are streamed to the replicas.
+// <fn> must not keep the RankTree.
+func (p *Primary) Update(fn func(tree *ranktree.RankTree)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(p.tree)
+}
+
+
+// View calls <fn> to read the RankTree.
+// <fn> must not keep the RankTree.
+func (p *Primary) View(fn func(tree ranktree.RankTreeView)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(p.tree)
+}
+
+
+// Returns the sequence number of the last op.
+func (p *Primary) Seq() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.seq
+}
+
+
+// Close stops recording the changes, Serve() returns.
+func (p *Primary) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed == false {
+		p.closed = true
+		p.cancel()
+		p.cond.Broadcast()
+	}
+}
+
+
+// Serve serves a replica on <conn> until the replica disconnects or the Primary is closed,
+// in which cases it returns nil.
+// Returns ErrLagging if the replica reads too slowly to keep up with the backlog,
+// it should reconnect to do a full sync.
+func (p *Primary) Serve(conn io.ReadWriter) error {
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	fields, err := readMessage(r)
+	if err != nil {
+		return err
+	}
+	if len(fields) != 3 || fields[0] != "SYNC" {
+		return fmt.Errorf("replication: unexpected message %q", fields)
+	}
+	seq, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return fmt.Errorf("replication: invalid sequence number %q", fields[2])
+	}
+
+	next, err := p.sync(w, fields[1], seq)
+	if err != nil {
+		return err
+	}
+
+	// the replica sends nothing more, the read ends when it disconnects
+	gone := false
+	go func() {
+		io.Copy(io.Discard, r)
+		p.mu.Lock()
+		gone = true
+		p.cond.Broadcast()
+		p.mu.Unlock()
+	}()
+
+	for {
+		p.mu.Lock()
+		for next > p.seq && p.closed == false && gone == false {
+			p.cond.Wait()
+		}
+		if p.closed || gone {
+			p.mu.Unlock()
+			return nil
+		}
+		ops, err := p.opsFrom(next)
+		p.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		for _, o := range ops {
+			writeOp(w, o)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		next = ops[len(ops) - 1].seq + 1
+	}
+}
+
+
+// Sends a FULLSYNC or CONTINUE message to a replica which applied op <seq> of the Primary <id>.
+// Returns the sequence number of the next op to send.
+func (p *Primary) sync(w *bufio.Writer, id string, seq uint64) (next uint64, err error) {
+	p.mu.Lock()
+	if id == p.id && seq <= p.seq && p.seq - seq <= uint64(len(p.backlog)) {
+		p.mu.Unlock()
+		fmt.Fprintf(w, "CONTINUE %s %d\n", p.id, seq)
+		return seq + 1, w.Flush()
+	}
+
+	// the snapshot is written without the lock
+	view := p.tree.Snapshot()
+	seq = p.seq
+	p.mu.Unlock()
+
+	var b bytes.Buffer
+	view.WriteTo(&b)
+	fmt.Fprintf(w, "FULLSYNC %s %d %d\n", p.id, seq, b.Len())
+	w.Write(b.Bytes())
+	return seq + 1, w.Flush()
+}
+
+
+// Returns the ops from <next> to the last one, must be called with the lock held.
+// Returns ErrLagging if op <next> is no longer in the backlog.
+func (p *Primary) opsFrom(next uint64) ([]op, error) {
+	n := uint64(len(p.backlog))
+	if next + n <= p.seq {
+		return nil, ErrLagging
+	}
+
+	ops := make([]op, 0, p.seq - next + 1)
+	for seq := next; seq <= p.seq; seq++ {
+		ops = append(ops, p.backlog[(seq - 1) % n])
+	}
+	return ops, nil
+}
@@ -0,0 +1,175 @@
+package replication
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// Starts Serve() on a pipe, returns the replica side and the result of Serve().
+func servePipe(p *Primary) (conn net.Conn, done chan error) {
+	conn, server := net.Pipe()
+	done = make(chan error, 1)
+	go func() {
+		done <- p.Serve(server)
+		server.Close()
+	}()
+	return conn, done
+}
+
+
+// Returns the result of Serve() or fails after a timeout.
+func waitServe(t *testing.T, done chan error) error {
+	t.Helper()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return")
+		return nil
+	}
+}
+
+
+// Sends SYNC <id> <seq>, returns the answer line.
+func sendSync(t *testing.T, conn net.Conn, r *bufio.Reader, id string, seq uint64) string {
+	t.Helper()
+
+	fmt.Fprintf(conn, "SYNC %s %d\n", id, seq)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	return line
+}
+
+
+func TestPrimary_Serve(t *testing.T) {
+	tree, _ := ranktree.New(0, 100)
+	tree.Add("alice", 10)
+	p := NewPrimary(tree, 2)
+	defer p.Close()
+
+	p.Update(func(tree *ranktree.RankTree) {
+		tree.Add("bob", 20)
+		tree.IncrementBy("alice", 5)
+	})
+	if p.Seq() != 2 {
+		t.Fatalf("Seq() = %d, want 2", p.Seq())
+	}
+
+	// full sync of a new replica
+	conn, done := servePipe(p)
+	r := bufio.NewReader(conn)
+	body := "ranktree 1 0 100 2\n15 \"alice\"\n20 \"bob\"\n"
+	if line, want := sendSync(t, conn, r, "-", 0), fmt.Sprintf("FULLSYNC %s 2 %d\n", p.id, len(body)); line != want {
+		t.Fatalf("answer = %q, want %q", line, want)
+	}
+	b := make([]byte, len(body))
+	if _, err := io.ReadFull(r, b); err != nil || string(b) != body {
+		t.Fatalf("full sync = %q, %v", b, err)
+	}
+
+	// then the ops
+	p.Update(func(tree *ranktree.RankTree) {
+		tree.Add("charles darwin", 30)
+		tree.Remove("bob")
+	})
+	for _, want := range []string{"OP 3 30 \"charles darwin\"\n", "OP 4 -1 \"bob\"\n"} {
+		if line, _ := r.ReadString('\n'); line != want {
+			t.Errorf("op = %q, want %q", line, want)
+		}
+	}
+
+	// Serve() returns when the replica disconnects
+	conn.Close()
+	if err := waitServe(t, done); err != nil {
+		t.Errorf("Serve() = %v after the replica disconnected", err)
+	}
+
+	// resume from the backlog
+	conn, done = servePipe(p)
+	r = bufio.NewReader(conn)
+	if line, want := sendSync(t, conn, r, p.id, 2), fmt.Sprintf("CONTINUE %s 2\n", p.id); line != want {
+		t.Errorf("answer = %q, want %q", line, want)
+	}
+	if line, _ := r.ReadString('\n'); line != "OP 3 30 \"charles darwin\"\n" {
+		t.Errorf("op = %q after CONTINUE", line)
+	}
+	conn.Close()
+	waitServe(t, done)
+
+	// full sync when the backlog does not have the next op, for another primary or a future op
+	for _, sync := range []struct {
+		id	string
+		seq	uint64
+	}{{p.id, 1}, {"other", 4}, {p.id, 5}, {p.id, 0}} {
+		conn, done = servePipe(p)
+		r = bufio.NewReader(conn)
+		if line := sendSync(t, conn, r, sync.id, sync.seq); strings.HasPrefix(line, "FULLSYNC " + p.id + " 4 ") == false {
+			t.Errorf("answer to SYNC %s %d = %q, want a full sync", sync.id, sync.seq, line)
+		}
+		conn.Close()
+		waitServe(t, done)
+	}
+
+	// Serve() returns when the primary is closed
+	conn, done = servePipe(p)
+	r = bufio.NewReader(conn)
+	sendSync(t, conn, r, p.id, 4)
+	p.Close()
+	if err := waitServe(t, done); err != nil {
+		t.Errorf("Serve() = %v after Close()", err)
+	}
+	conn.Close()
+}
+
+
+func TestPrimary_Lagging(t *testing.T) {
+	tree, _ := ranktree.New(0, 100)
+	p := NewPrimary(tree, 2)
+	defer p.Close()
+
+	conn, done := servePipe(p)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	sendSync(t, conn, r, "-", 0)
+	io.ReadFull(r, make([]byte, len("ranktree 1 0 100 0\n")))
+
+	// the replica does not read while the ops overflow the backlog
+	p.Update(func(tree *ranktree.RankTree) {
+		for i := 0; i < 5; i++ {
+			tree.Add(fmt.Sprint(i), i)
+		}
+	})
+	go io.Copy(io.Discard, r)
+
+	if err := waitServe(t, done); errors.Is(err, ErrLagging) == false {
+		t.Errorf("Serve() = %v, want %v", err, ErrLagging)
+	}
+}
+
+
+func TestPrimary_ServeErrors(t *testing.T) {
+	tree, _ := ranktree.New(0, 100)
+	p := NewPrimary(tree, 10)
+	defer p.Close()
+
+	for _, line := range []string{"HELLO\n", "SYNC - x\n", "SYNC 0\n"} {
+		conn, done := servePipe(p)
+		io.WriteString(conn, line)
+		if err := waitServe(t, done); err == nil {
+			t.Errorf("Serve() of %q succeeded", line)
+		}
+		conn.Close()
+	}
+}
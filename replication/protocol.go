@@ -0,0 +1,96 @@
+// Package replication replicates a RankTree from a primary to read replicas over a stream,
+// e.g. a net.Conn.
+//
+// The Primary records every change of its RankTree as an op with a sequence number,
+// and keeps the latest ops in a backlog. A Replica connects with the sequence number of the last op it applied:
+// if the backlog still has the following ops, the Primary continues from there,
+// otherwise it sends a full copy of the tree from a snapshot first (full sync).
+// Then the Primary streams the ops as they happen, the Replica applies them in order,
+// and fails with ErrGap if an op is missing.
+//
+//	primary := replication.NewPrimary(tree, 10000)
+//	go primary.Serve(conn)
+//	primary.Update(func(tree *ranktree.RankTree) {
+//		tree.Add("Bob", 1234)
+//	})
+//
+//	replica := replication.NewReplica(ranktree.WithSkipList())
+//	go replica.Sync(conn)	// call it again with a new connection to resume
+//	replica.View(func(tree ranktree.RankTreeView) {
+//		top = tree.RevRange(0, 9)
+//	})
+//
+// The protocol is text, one message per line:
+//
+//	SYNC <id> <seq>				replica: the primary id and the sequence number of the last applied op, or "- 0"
+//	FULLSYNC <id> <seq> <size>	primary: followed by <size> bytes written by RankTree.WriteTo() at <seq>
+//	CONTINUE <id> <seq>			primary: the ops after <seq> follow
+//	OP <seq> <score> <member>	primary: the member is quoted as a Go string literal, score -1 removes it
+package replication
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+
+var (
+	ErrGap		= errors.New("replication: gap in the op stream")
+	ErrLagging	= errors.New("replication: replica lagging behind the backlog")
+	ErrClosed	= errors.New("replication: primary closed")
+)
+
+
+// op sets the score of a member, or removes it if the score is -1 (see ranktree.Event).
+type op struct {
+	seq		uint64
+	member	string
+	score	int
+}
+
+
+// Writes <o> as an OP line.
+func writeOp(w *bufio.Writer, o op) {
+	w.WriteString("OP ")
+	w.WriteString(strconv.FormatUint(o.seq, 10))
+	w.WriteByte(' ')
+	w.WriteString(strconv.Itoa(o.score))
+	w.WriteByte(' ')
+	w.WriteString(strconv.Quote(o.member))
+	w.WriteByte('\n')
+}
+
+
+// Parses an OP line without the newline.
+func parseOp(line string) (o op, err error) {
+	var seq, score, member string
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) != 4 || fields[0] != "OP" {
+		return op{}, fmt.Errorf("replication: unexpected message %q", line)
+	}
+	seq, score, member = fields[1], fields[2], fields[3]
+
+	if o.seq, err = strconv.ParseUint(seq, 10, 64); err != nil {
+		return op{}, fmt.Errorf("replication: invalid op %q", line)
+	}
+	if o.score, err = strconv.Atoi(score); err != nil || o.score < -1 {
+		return op{}, fmt.Errorf("replication: invalid op %q", line)
+	}
+	if o.member, err = strconv.Unquote(member); err != nil {
+		return op{}, fmt.Errorf("replication: invalid op %q", line)
+	}
+	return o, nil
+}
+
+
+// Reads a message line, returns its fields.
+func readMessage(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(line, "\n"), " "), nil
+}
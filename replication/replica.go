@@ -0,0 +1,165 @@
+package replication
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// Replica applies the op stream of a Primary to its copy of the RankTree.
+type Replica struct {
+	mu		sync.RWMutex
+	tree	*ranktree.RankTree		// nil before the first full sync
+	id		string					// id of the Primary
+	seq		uint64					// sequence number of the last applied op
+	opts	[]ranktree.Option
+}
+
+
+// NewReplica creates an empty Replica, <opts> are passed to ranktree.Load() on a full sync.
+// The tree of a Replica is a skip list by default (see ranktree.WithSkipList()), so that it accepts
+// the score range of any Primary, e.g. [0, math.MaxInt]; <opts> may choose a dense backend
+// if the range of the Primary is known to suit it, see ranktree.ErrRangeTooLarge.
+// The score range follows the Primary by ranktree.WithAutoExtend().
+func NewReplica(opts ...ranktree.Option) *Replica {
+	opts = append([]ranktree.Option{ranktree.WithSkipList()}, opts...)
+	return &Replica{
+		opts:	append(opts, ranktree.WithAutoExtend()),
+	}
+}
+
+
+// Returns the sequence number of the last applied op.
+func (r *Replica) Seq() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.seq
+}
+
+
+// View calls <fn> to read the RankTree, the ops are not applied meanwhile.
+// <fn> must not keep the RankTree.
+// Returns false without calling <fn> if the Replica has not synced yet.
+func (r *Replica) View(fn func(tree ranktree.RankTreeView)) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.tree == nil {
+		return false
+	}
+	fn(r.tree)
+	return true
+}
+
+
+// Sync connects to a Primary on <conn>, resumes after the last applied op or does a full sync,
+// then applies the ops until the Primary disconnects, in which case it returns nil.
+// Returns an error wrapping ErrGap if an op is missing, the Replica is not changed by the op.
+// Sync can be called again with a new connection to resume.
+func (r *Replica) Sync(conn io.ReadWriter) error {
+	br := bufio.NewReader(conn)
+
+	id, seq := r.position()
+	if _, err := fmt.Fprintf(conn, "SYNC %s %d\n", id, seq); err != nil {
+		return err
+	}
+
+	if err := r.handshake(br, id, seq); err != nil {
+		return err
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		o, err := parseOp(strings.TrimSuffix(line, "\n"))
+		if err != nil {
+			return err
+		}
+		if err := r.apply(o); err != nil {
+			return err
+		}
+	}
+}
+
+
+// Returns the id of the Primary and the sequence number of the last applied op, or "-" and 0.
+func (r *Replica) position() (id string, seq uint64) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.tree == nil {
+		return "-", 0
+	}
+	return r.id, r.seq
+}
+
+
+// Reads the answer to SYNC <id> <seq>, loads the tree on a full sync.
+func (r *Replica) handshake(br *bufio.Reader, id string, seq uint64) error {
+	fields, err := readMessage(br)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(fields) == 3 && fields[0] == "CONTINUE":
+		if fields[1] != id || fields[2] != strconv.FormatUint(seq, 10) {
+			return fmt.Errorf("replication: unexpected message %q", fields)
+		}
+		return nil
+
+	case len(fields) == 4 && fields[0] == "FULLSYNC":
+		seq, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("replication: invalid sequence number %q", fields[2])
+		}
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil || size < 0 {
+			return fmt.Errorf("replication: invalid size %q", fields[3])
+		}
+
+		tree, err := ranktree.Load(io.LimitReader(br, size), r.opts...)
+		if err != nil {
+			return fmt.Errorf("replication: full sync: %w", err)
+		}
+
+		r.mu.Lock()
+		r.tree, r.id, r.seq = tree, fields[1], seq
+		r.mu.Unlock()
+		return nil
+	}
+	return fmt.Errorf("replication: unexpected message %q", fields)
+}
+
+
+// Applies <o>, which must follow the last applied op.
+func (r *Replica) apply(o op) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if o.seq != r.seq + 1 {
+		return fmt.Errorf("%w: op %d after op %d", ErrGap, o.seq, r.seq)
+	}
+
+	// the tree is not changed if the op fails
+	batch := []ranktree.Op{{Type: ranktree.OpUpdate, Member: o.member, Score: o.score, Insert: true}}
+	if o.score == -1 {
+		batch[0] = ranktree.Op{Type: ranktree.OpRemove, Member: o.member}
+	}
+	if _, err := r.tree.Apply(batch); err != nil {
+		return fmt.Errorf("replication: op %d: %w", o.seq, err)
+	}
+	r.seq = o.seq
+	return nil
+}
@@ -0,0 +1,201 @@
+package replication
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ng1091/ranktree"
+)
+
+
+// Starts Sync() of <r> on a pipe served by <p>, returns the connection of the primary and the result of Sync().
+func syncPipe(p *Primary, r *Replica) (server net.Conn, done chan error) {
+	conn, server := net.Pipe()
+	go p.Serve(server)
+
+	done = make(chan error, 1)
+	go func() {
+		done <- r.Sync(conn)
+		conn.Close()
+	}()
+	return server, done
+}
+
+
+// Waits until <r> applied the last op of <p>, then checks the trees are equal.
+func checkReplica(t *testing.T, p *Primary, r *Replica) {
+	t.Helper()
+
+	synced := func() bool {
+		return r.View(func(ranktree.RankTreeView) {}) && r.Seq() == p.Seq()
+	}
+	for deadline := time.Now().Add(5 * time.Second); synced() == false; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("replica at op %d, primary at op %d", r.Seq(), p.Seq())
+		}
+	}
+
+	p.View(func(want ranktree.RankTreeView) {
+		r.View(func(got ranktree.RankTreeView) {
+			for e := range ranktree.Diff(want, got) {
+				t.Errorf("replica differs: %+v", e)
+			}
+		})
+	})
+}
+
+
+func TestReplica_Sync(t *testing.T) {
+	tree, _ := ranktree.New(0, 100)
+	tree.Add("alice", 10)
+	tree.Add("bob", 20)
+	p := NewPrimary(tree, 100)
+	defer p.Close()
+
+	r := NewReplica(ranktree.WithSkipList())
+	if r.View(func(ranktree.RankTreeView) {}) {
+		t.Error("View() succeeded before the sync")
+	}
+
+	server, done := syncPipe(p, r)
+	checkReplica(t, p, r)
+
+	p.Update(func(tree *ranktree.RankTree) {
+		tree.Add("charles", 30)
+		tree.IncrementBy("alice", 15)
+		tree.UpdateScore("bob", 5, false)
+		tree.Remove("charles")
+		tree.Apply([]ranktree.Op{
+			{Type: ranktree.OpAdd, Member: "dave", Score: 40},
+			{Type: ranktree.OpIncrement, Member: "bob", Score: 1},
+		})
+		tree.ExtendRange(0, 1000)
+		tree.Add("eve \"e\"", 1000)
+	})
+	checkReplica(t, p, r)
+
+	// resume after the primary disconnected
+	server.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Sync() = %v after the primary disconnected", err)
+	}
+	p.Update(func(tree *ranktree.RankTree) {
+		tree.PopMax()
+		tree.ShrinkRange(0, 30, ranktree.ShrinkEvict)
+	})
+	server, done = syncPipe(p, r)
+	checkReplica(t, p, r)
+	server.Close()
+	<-done
+
+	// a full sync replaces the tree
+	other, _ := ranktree.New(0, 10)
+	other.Add("frank", 1)
+	q := NewPrimary(other, 100)
+	defer q.Close()
+	server, done = syncPipe(q, r)
+	checkReplica(t, q, r)
+	server.Close()
+	<-done
+}
+
+
+func TestReplica_Gap(t *testing.T) {
+	conn, primary := net.Pipe()
+	defer conn.Close()
+
+	// a primary which skips op 2
+	go func() {
+		br := bufio.NewReader(primary)
+		br.ReadString('\n')
+		body := "ranktree 1 0 100 1\n10 \"alice\"\n"
+		fmt.Fprintf(primary, "FULLSYNC x 0 %d\n%sOP 1 20 \"bob\"\nOP 3 30 \"charles\"\nOP 4 40 \"dave\"\n", len(body), body)
+	}()
+
+	r := NewReplica()
+	if err := r.Sync(conn); errors.Is(err, ErrGap) == false {
+		t.Errorf("Sync() = %v, want %v", err, ErrGap)
+	}
+	if r.Seq() != 1 {
+		t.Errorf("Seq() = %d, want 1", r.Seq())
+	}
+	r.View(func(tree ranktree.RankTreeView) {
+		if tree.Card() != 2 || tree.Score("bob") != 20 || tree.Score("charles") != -1 {
+			t.Errorf("tree after the gap: %v", tree.Range(0, -1))
+		}
+	})
+}
+
+
+func TestReplica_SyncErrors(t *testing.T) {
+	answers := []string{
+		"HELLO\n",
+		"CONTINUE x 5\n",
+		"FULLSYNC x 0 -1\n",
+		"FULLSYNC x 0 5\nhello",
+		"FULLSYNC x 0 0\nOP 1 x \"bob\"\n",
+		"FULLSYNC x 0 0\nOP 1 10 bob\n",
+	}
+	for _, answer := range answers {
+		conn, primary := net.Pipe()
+		go func() {
+			bufio.NewReader(primary).ReadString('\n')
+			fmt.Fprint(primary, answer)
+			primary.Close()
+		}()
+
+		if err := NewReplica().Sync(conn); err == nil {
+			t.Errorf("Sync() succeeded on %q", answer)
+		}
+		conn.Close()
+	}
+}
+
+
+func TestReplica_Options(t *testing.T) {
+	// the default skip list accepts any range of a primary
+	r := NewReplica()
+	body := "ranktree 1 0 9223372036854775807 1\n10 \"alice\"\n"
+	answer := fmt.Sprintf("FULLSYNC x 0 %d\n%sOP 1 1099511627776 \"bob\"\n", len(body), body)
+	if err := syncAnswer(r, answer); err != nil || r.Seq() != 1 {
+		t.Errorf("Sync() of the range [0, MaxInt] = %v, seq %d", err, r.Seq())
+	}
+
+	// a failed op changes nothing
+	r = NewReplica(ranktree.WithFenwick())
+	body = "ranktree 1 0 100 1\n10 \"alice\"\n"
+	answer = fmt.Sprintf("FULLSYNC x 0 %d\n%sOP 1 1099511627776 \"alice\"\n", len(body), body)
+	if err := syncAnswer(r, answer); errors.Is(err, ranktree.ErrOutOfRange) == false {
+		t.Errorf("Sync() = %v, want %v", err, ranktree.ErrOutOfRange)
+	}
+	r.View(func(tree ranktree.RankTreeView) {
+		if tree.Score("alice") != 10 || r.seq != 0 {
+			t.Errorf("Score(\"alice\") = %d, seq %d after a failed op", tree.Score("alice"), r.seq)
+		}
+	})
+
+	// the options of the caller are not written
+	opts := make([]ranktree.Option, 1, 3)
+	opts[0] = ranktree.WithFenwick()
+	NewReplica(opts...)
+	if opts[:3][1] != nil || opts[:3][2] != nil {
+		t.Error("NewReplica() wrote the backing array of the options")
+	}
+}
+
+
+// Runs Sync() of <r> against a primary which answers <answer> and disconnects.
+func syncAnswer(r *Replica, answer string) error {
+	conn, primary := net.Pipe()
+	defer conn.Close()
+	go func() {
+		bufio.NewReader(primary).ReadString('\n')
+		fmt.Fprint(primary, answer)
+		primary.Close()
+	}()
+	return r.Sync(conn)
+}
@@ -0,0 +1,182 @@
+package ranktree
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+
+// ErrRangeTooLarge is returned by New() and ExtendRange() when the score range is too large for the backend:
+// the default segment tree and WithFenwick() take memory for every score, their range is limited to 2^24 scores.
+// New() without a backend option falls back to WithSkipList() instead, use it to extend to a huge range.
+var ErrRangeTooLarge = errors.New("score range too large for the backend")
+
+
+// ExtendRange extends the score range of the RankTree to cover [newLow, newHigh].
+// The range never shrinks, i.e. the new range is the union of the current range and [newLow, newHigh].
+// The tree grows by creating new roots above the existing root, doubling the span as needed,
+// members and their order are preserved without re-adding.
+// If the existing root can not be a child of the new root, the tree is rebuilt reusing the leaf nodes.
+// Returns ErrRangeTooLarge without changing the tree if the extended range is too large for the backend.
+func (tree *RankTree) ExtendRange(newLow, newHigh int) error {
+	if newLow < 0 || newHigh < 0 {
+		return errors.New("low, high must be non-negative")
+	}
+
+	if newLow > newHigh {
+		return errors.New("low greater than high")
+	}
+
+	if newLow > tree.minScore {
+		newLow = tree.minScore
+	}
+
+	if newHigh < tree.maxScore {
+		newHigh = tree.maxScore
+	}
+
+	low, high, rebuild := tree.extendedRange(newLow, newHigh)
+	if rangeTooLarge(tree.backend, low, high) {
+		return ErrRangeTooLarge
+	}
+
+	if rebuild {
+		tree.rebuild(low, high)
+	} else {
+		// grow downward, the old root becomes the right child
+		for tree.low > low {
+			tree.grow(tree.low - (tree.high - tree.low + 1), tree.high, true)
+		}
+
+		// grow upward, the old root becomes the left child
+		for tree.high < high {
+			tree.grow(tree.low, tree.high + (tree.high - tree.low + 1), false)
+		}
+	}
+
+	tree.minScore = newLow
+	tree.maxScore = newHigh
+	return nil
+}
+
+
+// Returns the range of the root after ExtendRange(newLow, newHigh), doubling the span as needed,
+// and whether the tree must be rebuilt, because the existing root can not be a child of the new root.
+func (tree *RankTree) extendedRange(newLow, newHigh int) (low, high int, rebuild bool) {
+	low, high = tree.low, tree.high
+
+	// downward, while the span fits above 0
+	for newLow < low && low >= high - low + 1 {
+		low -= high - low + 1
+	}
+
+	if newLow < low {
+		return newLow, max(high, newHigh), true
+	}
+
+	// upward, while the span fits below math.MaxInt
+	for newHigh > high {
+		span := high - low + 1
+		if high > math.MaxInt - span {
+			return low, newHigh, true
+		}
+		high += span
+	}
+	return low, high, false
+}
+
+
+// Rebuilds the tree with the score range [low, high].
+// Non-empty leaf nodes are reused, so that the linked list is preserved.
+func (tree *RankTree) rebuild(low, high int) {
+	tree.create(low, high)
+
+	for leaf := tree.list.head(); leaf != nil; leaf = tree.list.next(leaf) {
+		tree.backend.setLeaf(leaf.score - low, leaf)
+		tree.incrementCount(leaf.score, len(leaf.members))
+	}
+}
+
+
+// Grows the tree to the range [low, high] with a new root, which has the same span on both sides.
+// The old root becomes the right child if <right> is true, otherwise the left child.
+func (tree *RankTree) grow(low, high int, right bool) {
+	tree.backend.grow(right)
+	tree.low = low
+	tree.high = high
+}
+
+
+// ShrinkPolicy decides what ShrinkRange() does with members out of the new range.
+type ShrinkPolicy int
+
+const (
+	ShrinkReject	ShrinkPolicy = iota	// fail if any member is out of the new range
+	ShrinkEvict							// remove members out of the new range
+)
+
+
+// ShrinkRange shrinks the score range of the RankTree to [newLow, newHigh],
+// and releases the nodes out of the new range.
+// [newLow, newHigh] is limited to the current range.
+// If there are members out of the new range, an error is returned with ShrinkReject,
+// or they are removed and returned with ShrinkEvict.
+func (tree *RankTree) ShrinkRange(newLow, newHigh int, policy ShrinkPolicy) (evicted []RankWithScore, err error) {
+	if newLow < tree.minScore {
+		newLow = tree.minScore
+	}
+
+	if newHigh > tree.maxScore {
+		newHigh = tree.maxScore
+	}
+
+	if newLow > newHigh {
+		return nil, errors.New("new range out of the range")
+	}
+
+	outside := tree.countLeftArea(newLow) + tree.countRightArea(newHigh)
+	if outside > 0 {
+		if policy != ShrinkEvict {
+			return nil, fmt.Errorf("%d member(s) out of the new range", outside)
+		}
+
+		evicted = tree.RangeByScore(tree.minScore, newLow - 1)
+		if newHigh < tree.maxScore {
+			evicted = append(evicted, tree.RangeByScore(newHigh + 1, tree.maxScore)...)
+		}
+		for _, v := range evicted {
+			tree.Remove(v.Member)
+		}
+	}
+
+	// release nodes out of the new range
+	tree.rebuild(newLow, newHigh)
+
+	tree.minScore = newLow
+	tree.maxScore = newHigh
+	return evicted, nil
+}
+
+
+// Compact moves the non-empty leaf nodes of the RankTree into a single contiguous block of memory,
+// in score order, and releases the empty ones, which cuts the allocation overhead and improves the cache locality.
+// Members, ranks and the score range are not changed, but Iterators are invalidated.
+func (tree *RankTree) Compact() {
+	nodes := make([]TreeNode, tree.list.len)
+	i := len(nodes) - 1
+	for node := tree.list.head(); node != nil; node = tree.list.next(node) {
+		n := &nodes[i]
+		*n = *node
+		if n.gen != tree.gen {
+			n.members = slices.Clone(node.members)
+			n.gen = tree.gen
+		}
+		tree.list.replace(n, tree.gen)
+		i--
+	}
+
+	// release the empty leaf nodes
+	tree.rebuild(tree.low, tree.high)
+}
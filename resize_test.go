@@ -0,0 +1,340 @@
+package ranktree
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+
+// Checks counts of the backend and scores of the leaf nodes of <tree>.
+func checkTreeCounts(t *testing.T, tree *RankTree) {
+	sum := 0
+	for i := 0; i <= tree.high - tree.low; i++ {
+		node := tree.backend.leaf(i)
+		count := tree.backend.prefix(i + 1) - tree.backend.prefix(i)
+		if node == nil && count != 0 {
+			t.Errorf("leaf (%d) count = %d, want 0", tree.low + i, count)
+		}
+		if node != nil && (node.score != tree.low + i || len(node.members) != count) {
+			t.Errorf("leaf (%d) score = %d, count = %d, want %d", tree.low + i, node.score, count, len(node.members))
+		}
+		sum += count
+	}
+
+	if n := tree.backend.prefix(tree.high - tree.low + 1); n != tree.count || sum != tree.count {
+		t.Errorf("backend count = %d, want %d", n, tree.count)
+	}
+
+	if seg, ok := tree.backend.(*segmentTree); ok {
+		if n := segmentTreeLen(seg.size); seg.counts.size != n || seg.size != tree.high - tree.low + 1 {
+			t.Errorf("seg.counts.size = %d, want %d", seg.counts.size, n)
+		}
+		checkNodeCounts(t, seg, 0, 0, seg.size - 1)
+	}
+
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+
+// Checks counts of the subtree of node i with the range [low, high].
+// Returns the count of node i.
+func checkNodeCounts(t *testing.T, seg *segmentTree, i, low, high int) int {
+	count := int(seg.counts.get(i))
+	if low == high {
+		return count
+	}
+
+	mid := low + (high - low) / 2
+	if n := checkNodeCounts(t, seg, 2 * i + 1, low, mid) + checkNodeCounts(t, seg, 2 * i + 2, mid + 1, high); count != n {
+		t.Errorf("node (%d, %d) count = %d, want %d", low, high, count, n)
+	}
+	return count
+}
+
+
+func newResizeTestTree(t *testing.T, low, high int, opts ...Option) *RankTree {
+	tree, err := New(low, high, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := low; i <= high; i++ {
+		tree.Add(fmt.Sprintf("m%d", i), i)
+		tree.Add(fmt.Sprintf("n%d", i), i)
+	}
+	return tree
+}
+
+
+func checkExtended(t *testing.T, tree *RankTree, low, high int, members []string, leaves map[string]*TreeNode) {
+	if tree.minScore != low || tree.maxScore != high {
+		t.Errorf("range = (%d, %d), want (%d, %d)", tree.minScore, tree.maxScore, low, high)
+	}
+
+	checkRankTree(t, tree, tree.low, tree.high, len(members))
+	checkTreeCounts(t, tree)
+	checkListOrder(t, tree)
+	checkRank(t, tree.Range(0, -1), members)
+
+	for member, node := range leaves {
+		if n, _ := tree.node(member); n != node {
+			t.Errorf("node of %s changed", member)
+		}
+	}
+}
+
+
+func TestRankTree_ExtendRange(t *testing.T) {
+	tree := newResizeTestTree(t, 16, 23)
+	members := tree.Range(0, -1)
+	leaves := make(map[string]*TreeNode)
+	for k := range tree.scores.all() {
+		leaves[k], _ = tree.node(k)
+	}
+
+	// up
+	if err := tree.ExtendRange(20, 40); err != nil {
+		t.Fatal(err)
+	}
+	checkExtended(t, tree, 16, 40, members, leaves)
+	if tree.low != 16 || tree.high != 47 {
+		t.Errorf("root = (%d, %d), want (16, 47)", tree.low, tree.high)
+	}
+
+	// down
+	if err := tree.ExtendRange(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	checkExtended(t, tree, 0, 40, members, leaves)
+	if tree.low != 0 || tree.high != 47 {
+		t.Errorf("root = (%d, %d), want (0, 47)", tree.low, tree.high)
+	}
+
+	if tree.Add("x", 40) != true || tree.Add("y", 41) != false || tree.Add("z", 0) != true {
+		t.Error("tree.Add() in the extended range failed")
+	}
+
+	if tree.ExtendRange(-1, 10) == nil || tree.ExtendRange(10, 5) == nil {
+		t.Error("tree.ExtendRange() with an invalid range succeeded")
+	}
+}
+
+
+func TestRankTree_ExtendRangeDown(t *testing.T) {
+	tree := newResizeTestTree(t, 16, 23)
+	members := tree.Range(0, -1)
+	leaves := make(map[string]*TreeNode)
+	for k := range tree.scores.all() {
+		leaves[k], _ = tree.node(k)
+	}
+
+	// 16..23 -> 8..23 -> -8..23 is not allowed, rebuilt as 2..23
+	if err := tree.ExtendRange(8, 20); err != nil {
+		t.Fatal(err)
+	}
+	checkExtended(t, tree, 8, 23, members, leaves)
+	if tree.low != 8 || tree.high != 23 {
+		t.Errorf("root = (%d, %d), want (8, 23)", tree.low, tree.high)
+	}
+
+	if err := tree.ExtendRange(2, 2); err != nil {
+		t.Fatal(err)
+	}
+	checkExtended(t, tree, 2, 23, members, leaves)
+	if tree.low != 2 || tree.high != 23 {
+		t.Errorf("root = (%d, %d), want (2, 23)", tree.low, tree.high)
+	}
+}
+
+
+func TestRankTree_ExtendRangeRebuild(t *testing.T) {
+	tree := newResizeTestTree(t, 10, 20)
+	members := tree.Range(0, -1)
+	leaves := make(map[string]*TreeNode)
+	for k := range tree.scores.all() {
+		leaves[k], _ = tree.node(k)
+	}
+
+	// the old root does not fit as the right child
+	if err := tree.ExtendRange(5, 25); err != nil {
+		t.Fatal(err)
+	}
+	checkExtended(t, tree, 5, 25, members, leaves)
+	checkRankTree(t, tree, 5, 25, len(members))
+}
+
+
+func TestRankTree_AutoExtend(t *testing.T) {
+	tree, err := New(10, 20, WithAutoExtend())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 15)
+	if tree.Add("b", 100) != true || tree.Add("c", 3) != true || tree.Add("d", -1) != false {
+		t.Error("tree.Add() with auto extension failed")
+	}
+
+	if n := tree.IncrementBy("a", 1000); n != 1015 {
+		t.Errorf("tree.IncrementBy() = %d, want %d", n, 1015)
+	}
+
+	if _, err := tree.Apply([]Op{{Type: OpUpdate, Member: "c", Score: 5000}}); err != nil {
+		t.Error(err)
+	}
+
+	checkRankWithScore(t, tree.RangeWithScore(0, -1), []string{"b", "a", "c"}, []int{100, 1015, 5000})
+	checkTreeCounts(t, tree)
+	checkListOrder(t, tree)
+
+	if tree.minScore != 3 || tree.maxScore != 5000 {
+		t.Errorf("range = (%d, %d), want (3, 5000)", tree.minScore, tree.maxScore)
+	}
+}
+
+
+func TestRankTree_ExtendRangeTooLarge(t *testing.T) {
+	dense := map[string]Option{
+		"segment":	func(tree *RankTree) { tree.backend = newSegmentTree() },
+		"fenwick":	WithFenwick(),
+	}
+
+	for name, opt := range dense {
+		if _, err := New(0, 1 << 40, opt); errors.Is(err, ErrRangeTooLarge) == false {
+			t.Errorf("%s: New(0, 1 << 40) = %v, want %v", name, err, ErrRangeTooLarge)
+		}
+
+		tree, err := New(0, 100, opt, WithAutoExtend())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.Add("a", 10)
+
+		if tree.Add("b", 1 << 40) != false || tree.IncrementBy("a", 1 << 40) != -1 {
+			t.Errorf("%s: tree.Add() of a score too large for the backend succeeded", name)
+		}
+		if err := tree.ExtendRange(0, 1 << 40); errors.Is(err, ErrRangeTooLarge) == false {
+			t.Errorf("%s: tree.ExtendRange(0, 1 << 40) = %v, want %v", name, err, ErrRangeTooLarge)
+		}
+		_, err = tree.Apply([]Op{{Type: OpAdd, Member: "c", Score: 5}, {Type: OpAdd, Member: "d", Score: 1 << 40}})
+		if errors.Is(err, ErrOutOfRange) == false || errors.Is(err, ErrRangeTooLarge) == false {
+			t.Errorf("%s: tree.Apply() = %v, want %v", name, err, ErrRangeTooLarge)
+		}
+
+		// the range is not changed
+		if tree.low != 0 || tree.high != 100 || tree.maxScore != 100 {
+			t.Errorf("%s: range = (%d, %d), want (0, 100)", name, tree.low, tree.high)
+		}
+		checkTreeCounts(t, tree)
+	}
+
+	tree, _ := New(0, 100, WithSkipList(), WithAutoExtend())
+	if tree.Add("b", 1 << 40) != true {
+		t.Error("skiplist: tree.Add(\"b\", 1 << 40) failed")
+	}
+}
+
+
+func TestRankTree_NewLargeRange(t *testing.T) {
+	// without a backend option, a range too large for the dense backends falls back to the skip list
+	for _, high := range []int{maxDenseRange, 20_000_000, 1 << 40, math.MaxInt} {
+		tree, err := New(0, high)
+		if err != nil {
+			t.Fatalf("New(0, %d) = %v", high, err)
+		}
+		if tree.Add("a", high) == false || tree.Rank("a") != 0 {
+			t.Errorf("New(0, %d): tree.Add(\"a\", %d) failed", high, high)
+		}
+	}
+
+	// the dense backends accept exactly maxDenseRange scores
+	dense := newFenwickTree()
+	if rangeTooLarge(dense, 0, maxDenseRange - 1) || rangeTooLarge(dense, 5, maxDenseRange + 4) {
+		t.Errorf("%d scores are too large", maxDenseRange)
+	}
+	if rangeTooLarge(dense, 0, maxDenseRange) == false || rangeTooLarge(dense, 5, maxDenseRange + 5) == false {
+		t.Errorf("%d scores are not too large", maxDenseRange + 1)
+	}
+	if _, err := New(0, maxDenseRange, WithFenwick()); errors.Is(err, ErrRangeTooLarge) == false {
+		t.Errorf("New(0, %d, WithFenwick()) = %v, want %v", maxDenseRange, err, ErrRangeTooLarge)
+	}
+
+	// the difference does not overflow
+	if rangeTooLarge(dense, math.MinInt, math.MaxInt) == false || rangeTooLarge(newSkipList(), 0, math.MaxInt) {
+		t.Error("rangeTooLarge() overflowed")
+	}
+}
+
+
+func TestRankTree_ShrinkRange(t *testing.T) {
+	tree := newResizeTestTree(t, 0, 31)
+	if _, err := tree.ShrinkRange(8, 15, ShrinkReject); err == nil {
+		t.Error("tree.ShrinkRange() with members out of the range succeeded")
+	}
+	checkExtended(t, tree, 0, 31, tree.Range(0, -1), nil)
+
+	evicted, err := tree.ShrinkRange(8, 15, ShrinkEvict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 48 || evicted[0].Score != 0 || evicted[47].Score != 31 {
+		t.Errorf("evicted = %v, want 48 members with scores 0..7, 16..31", evicted)
+	}
+
+	// aligned with a subtree
+	checkExtended(t, tree, 8, 15, tree.Range(0, -1), nil)
+	checkRankTree(t, tree, 8, 15, 16)
+	if tree.Add("x", 16) != false || tree.Add("y", 7) != false {
+		t.Error("tree.Add() out of the shrunk range succeeded")
+	}
+
+	// not aligned with a subtree
+	leaves := make(map[string]*TreeNode)
+	for k := range tree.scores.all() {
+		leaves[k], _ = tree.node(k)
+	}
+	if _, err := tree.ShrinkRange(9, 20, ShrinkEvict); err != nil {
+		t.Fatal(err)
+	}
+	delete(leaves, "m8")
+	delete(leaves, "n8")
+	checkExtended(t, tree, 9, 15, tree.Range(0, -1), leaves)
+	checkRankTree(t, tree, 9, 15, 14)
+
+	if _, err := tree.ShrinkRange(20, 30, ShrinkEvict); err == nil {
+		t.Error("tree.ShrinkRange() out of the range succeeded")
+	}
+}
+
+
+func TestRankTree_Compact(t *testing.T) {
+	tree := newResizeTestTree(t, 0, 100)
+	tree.ExtendRange(0, 150)
+	tree.Remove("m3", "n3", "m50")
+	members := tree.RangeWithScore(0, -1)
+	names := tree.Range(0, -1)
+
+	tree.Compact()
+
+	checkRankTree(t, tree, tree.low, tree.high, len(members))
+	checkTreeCounts(t, tree)
+	checkListOrder(t, tree)
+	checkRankWithScore(t, tree.RangeWithScore(0, -1), names, scoresOf(members))
+	for _, v := range members {
+		if node, _ := tree.node(v.Member); node != tree.find(v.Score) || node.gen != tree.gen {
+			t.Errorf("node of %s = %p, want %p", v.Member, node, tree.find(v.Score))
+		}
+	}
+
+	tree.Add("x", 150)
+	tree.Remove("m0")
+	if n := tree.RevRank("x"); n != 0 {
+		t.Errorf("tree.RevRank(\"x\") = %d, want 0", n)
+	}
+	checkTreeCounts(t, tree)
+	checkListOrder(t, tree)
+}
@@ -0,0 +1,229 @@
+package ranktree
+
+import (
+	"hash/maphash"
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+
+// scoreMap maps the members of a RankTree to their scores. It is a hash array mapped trie,
+// whose nodes are copied on write like a cowArray, so that a write copies only the nodes on the path of the member.
+type scoreMap struct {
+	root	*scoreMapNode
+	len		int			// number of members
+}
+
+
+// scoreMapNode holds the entries of the hashes with the same scoreMapBits bits at each level above it.
+// Below the last level, it holds the members with the same hash in any order.
+type scoreMapNode struct {
+	gen		uint64			// generation which may write the node in place
+	bitmap	uint64			// bits of the hashes which have an entry
+	entries	[]scoreMapEntry	// in bit order
+}
+
+
+// scoreMapEntry is a member with its score, or a child node.
+type scoreMapEntry struct {
+	child	*scoreMapNode
+	hash	uint64
+	member	string
+	score	int
+}
+
+
+const scoreMapBits = 6	// hash bits of a level, the bitmap has one bit per value
+
+
+var scoreMapSeed = maphash.MakeSeed()
+
+
+// scoreMapHash returns the hash of a member, tests replace it to collide.
+var scoreMapHash = func(member string) uint64 {
+	return maphash.String(scoreMapSeed, member)
+}
+
+
+// Returns the score of <member>, and whether it exists.
+func (m *scoreMap) get(member string) (score int, ok bool) {
+	h := scoreMapHash(member)
+	n := m.root
+	for shift := 0; n != nil; shift += scoreMapBits {
+		if shift >= 64 {
+			for _, e := range n.entries {
+				if e.member == member {
+					return e.score, true
+				}
+			}
+			return 0, false
+		}
+
+		bit, i := n.index(h, shift)
+		if n.bitmap & bit == 0 {
+			return 0, false
+		}
+		e := &n.entries[i]
+		if e.child == nil {
+			return e.score, e.hash == h && e.member == member
+		}
+		n = e.child
+	}
+	return 0, false
+}
+
+
+// Sets the score of <member> with <gen>, it is added if it does not exist.
+func (m *scoreMap) set(member string, score int, gen uint64) {
+	h := scoreMapHash(member)
+	next := &m.root
+	for shift := 0; ; shift += scoreMapBits {
+		n := ownScoreMapNode(next, gen)
+		if shift >= 64 {
+			for i := range n.entries {
+				if n.entries[i].member == member {
+					n.entries[i].score = score
+					return
+				}
+			}
+			n.entries = append(n.entries, scoreMapEntry{hash: h, member: member, score: score})
+			m.len++
+			return
+		}
+
+		bit, i := n.index(h, shift)
+		if n.bitmap & bit == 0 {
+			n.bitmap |= bit
+			n.entries = slices.Insert(n.entries, i, scoreMapEntry{hash: h, member: member, score: score})
+			m.len++
+			return
+		}
+
+		e := &n.entries[i]
+		if e.child == nil {
+			if e.hash == h && e.member == member {
+				e.score = score
+				return
+			}
+
+			// push the member down to a child, which is split again at the next level if needed
+			child := &scoreMapNode{gen: gen, entries: []scoreMapEntry{*e}}
+			if shift + scoreMapBits < 64 {
+				child.bitmap, _ = child.index(e.hash, shift + scoreMapBits)
+			}
+			*e = scoreMapEntry{child: child}
+		}
+		next = &e.child
+	}
+}
+
+
+// Deletes <member> with <gen>.
+// Returns false if it does not exist.
+func (m *scoreMap) delete(member string, gen uint64) bool {
+	root, ok := m.root.delete(scoreMapHash(member), member, 0, gen)
+	if ok {
+		m.root = root
+		m.len--
+	}
+	return ok
+}
+
+
+// Deletes <member> with the hash <h> from the subtree of <n> at <shift>,
+// the nodes are copied only if it exists.
+// Returns the new node, nil if it is empty, and whether the member existed.
+func (n *scoreMapNode) delete(h uint64, member string, shift int, gen uint64) (*scoreMapNode, bool) {
+	if n == nil {
+		return n, false
+	}
+
+	if shift >= 64 {
+		i := slices.IndexFunc(n.entries, func(e scoreMapEntry) bool { return e.member == member })
+		if i < 0 {
+			return n, false
+		}
+		n = ownScoreMapNode(&n, gen)
+		n.entries = slices.Delete(n.entries, i, i + 1)
+	} else {
+		bit, i := n.index(h, shift)
+		if n.bitmap & bit == 0 {
+			return n, false
+		}
+
+		e := n.entries[i]
+		var child *scoreMapNode
+		if e.child != nil {
+			var ok bool
+			if child, ok = e.child.delete(h, member, shift + scoreMapBits, gen); ok == false {
+				return n, false
+			}
+		} else if e.hash != h || e.member != member {
+			return n, false
+		}
+
+		n = ownScoreMapNode(&n, gen)
+		if child != nil {
+			n.entries[i].child = child
+		} else {
+			n.bitmap &^= bit
+			n.entries = slices.Delete(n.entries, i, i + 1)
+		}
+	}
+
+	if len(n.entries) == 0 {
+		return nil, true
+	}
+	return n, true
+}
+
+
+// Returns all the members and their scores in no particular order.
+func (m *scoreMap) all() iter.Seq2[string, int] {
+	return func(yield func(string, int) bool) {
+		m.root.all(yield)
+	}
+}
+
+
+// Basic Function of all(), returns false if <yield> stopped.
+func (n *scoreMapNode) all(yield func(string, int) bool) bool {
+	if n == nil {
+		return true
+	}
+	for _, e := range n.entries {
+		if e.child != nil {
+			if e.child.all(yield) == false {
+				return false
+			}
+		} else if yield(e.member, e.score) == false {
+			return false
+		}
+	}
+	return true
+}
+
+
+// Returns the bit of the hash <h> at <shift> and the index of its entry.
+func (n *scoreMapNode) index(h uint64, shift int) (bit uint64, i int) {
+	bit = 1 << ((h >> shift) & (1 << scoreMapBits - 1))
+	return bit, bits.OnesCount64(n.bitmap & (bit - 1))
+}
+
+
+// Returns the node at *pp to write it with <gen>, it is allocated if missing, or replaced by a copy
+// if it belongs to another generation.
+func ownScoreMapNode(pp **scoreMapNode, gen uint64) *scoreMapNode {
+	n := *pp
+	switch {
+	case n == nil:
+		n = &scoreMapNode{gen: gen}
+	case n.gen != gen:
+		n = &scoreMapNode{gen: gen, bitmap: n.bitmap, entries: slices.Clone(n.entries)}
+	default:
+		return n
+	}
+	*pp = n
+	return n
+}
@@ -0,0 +1,103 @@
+package ranktree
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"testing"
+)
+
+
+// Checks <m> against <want>.
+func checkScoreMap(t *testing.T, m *scoreMap, want map[string]int) {
+	t.Helper()
+
+	if m.len != len(want) {
+		t.Errorf("len = %d, want %d", m.len, len(want))
+	}
+	for member, score := range want {
+		if v, ok := m.get(member); ok == false || v != score {
+			t.Errorf("get(%q) = (%d, %v), want (%d, true)", member, v, ok, score)
+		}
+	}
+	if got := maps.Collect(m.all()); maps.Equal(got, want) == false {
+		t.Errorf("all() = %d members, want %d", len(got), len(want))
+	}
+}
+
+
+func TestScoreMap(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	gen := newGen()
+	var m scoreMap
+	want := make(map[string]int)
+
+	var old scoreMap
+	var oldWant map[string]int
+	for i := 0; i < 20000; i++ {
+		member := fmt.Sprint(r.Intn(5000))
+		if r.Intn(3) == 0 {
+			_, ok := want[member]
+			if m.delete(member, gen) != ok {
+				t.Fatalf("delete(%q) != %v", member, ok)
+			}
+			delete(want, member)
+		} else {
+			want[member] = r.Intn(100)
+			m.set(member, want[member], gen)
+		}
+
+		// a copy is not changed by the writes of a new generation
+		if i == 10000 {
+			old, oldWant = m, maps.Clone(want)
+			gen = newGen()
+		}
+	}
+
+	checkScoreMap(t, &m, want)
+	checkScoreMap(t, &old, oldWant)
+	if _, ok := m.get("x"); ok || m.delete("x", gen) {
+		t.Error("get(\"x\") or delete(\"x\") found a missing member")
+	}
+
+	for member := range want {
+		m.delete(member, gen)
+	}
+	if m.root != nil || m.len != 0 {
+		t.Errorf("root = %v, len = %d after deleting every member", m.root, m.len)
+	}
+	checkScoreMap(t, &old, oldWant)
+}
+
+
+func TestScoreMap_Collisions(t *testing.T) {
+	defer func(hash func(string) uint64) { scoreMapHash = hash }(scoreMapHash)
+
+	// the same hash for every member, and hashes which differ only in the last bits
+	hashes := []func(string) uint64{
+		func(string) uint64 { return 42 },
+		func(member string) uint64 { return uint64(len(member)) << 60 },
+	}
+	for _, hash := range hashes {
+		scoreMapHash = hash
+		gen := newGen()
+		var m scoreMap
+		want := make(map[string]int)
+		for i := 0; i < 100; i++ {
+			member := fmt.Sprint(i)
+			m.set(member, i, gen)
+			want[member] = i
+		}
+		old, oldWant := m, maps.Clone(want)
+
+		gen = newGen()
+		for i := 0; i < 100; i += 3 {
+			m.delete(fmt.Sprint(i), gen)
+			delete(want, fmt.Sprint(i))
+		}
+		m.set("1", -1, gen)
+		want["1"] = -1
+		checkScoreMap(t, &m, want)
+		checkScoreMap(t, &old, oldWant)
+	}
+}
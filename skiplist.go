@@ -0,0 +1,298 @@
+package ranktree
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+)
+
+
+const (
+	skipListMaxLevel	= 32	// enough for 2^64 leaf nodes with skipListP = 1/4
+	skipListP			= 4		// a node has level i+1 with probability 1/skipListP of level i
+)
+
+
+// skipList is a backend based on a skip list of the leaf nodes, like zskiplist of Redis.
+// Only the non-empty leaf nodes take memory, which suits sparse scores in a huge range.
+// The span of a level is the number of members skipped by the link, so ranks are counted on the way.
+// The nodes are stored in a cowArray and linked by index, so that they are copied on write.
+type skipList struct {
+	nodes	cowArray[skipListNode]	// node 0 is the head, a sentinel whose levels above <level> are not used
+	free	[]int					// indexes of deleted nodes
+	level	int						// number of levels in use
+	total	int						// number of members
+	size	int						// number of positions
+	gen		uint64					// generation of the storage of the backend, see share()
+}
+
+
+// skipListNode is a leaf node in a skipList.
+type skipListNode struct {
+	pos		int
+	count	int
+	leaf	*TreeNode
+	levels	[]skipListLevel
+	gen		uint64			// generation of levels, which are not copied with the page of the node
+}
+
+
+type skipListLevel struct {
+	forward	int				// index of the next node, 0 if there is none
+	span	int				// number of members of the nodes in (this node, forward]
+}
+
+
+func newSkipList() backend {
+	return new(skipList)
+}
+
+
+// WithSkipList makes the RankTree store the leaf nodes in a skip list instead of the default segment tree.
+// Memory is taken only by the scores in use, not by the score range,
+// so New(0, math.MaxInt, WithSkipList()) accepts any non-negative score, see NewUnbounded().
+// Rank(), Count() etc. cost O(log(N)) on average, where N is the number of distinct scores.
+func WithSkipList() Option {
+	return func(tree *RankTree) {
+		tree.backend = newSkipList()
+	}
+}
+
+
+// NewUnbounded creates a RankTree without a declared score range, e.g. for timestamps or 64-bit ratings:
+// the leaf nodes are stored in a skip list (see WithSkipList()) covering every non-negative score.
+// Negative scores are not supported, as -1 stands for an absent member, e.g. in Score().
+// <opts> are passed to New(), a dense backend fails with ErrRangeTooLarge.
+func NewUnbounded(opts ...Option) (*RankTree, error) {
+	return New(0, math.MaxInt, append([]Option{WithSkipList()}, opts...)...)
+}
+
+
+func (sl *skipList) reset(size int) {
+	sl.gen = newGen()
+	sl.nodes = newCowArray[skipListNode](1)
+	sl.nodes.set(0, skipListNode{pos: -1, levels: make([]skipListLevel, skipListMaxLevel), gen: sl.gen}, sl.gen)
+	sl.free = nil
+	sl.level = 1
+	sl.total = 0
+	sl.size = size
+}
+
+
+// Only the scores in use take memory, so any range is accepted.
+func (sl *skipList) maxRange() int {
+	return math.MaxInt
+}
+
+
+func (sl *skipList) grow(upper bool) {
+	if upper {
+		for x := sl.node(0).levels[0].forward; x != 0; x = sl.node(x).levels[0].forward {
+			sl.edit(x).pos += sl.size
+		}
+	}
+	sl.size *= 2
+}
+
+
+func (sl *skipList) empty() backend {
+	return newSkipList()
+}
+
+
+func (sl *skipList) share() backend {
+	c := *sl
+	sl.gen = newGen()
+	return &c
+}
+
+
+// Returns the node <x>, which must not be written.
+func (sl *skipList) node(x int) *skipListNode {
+	return sl.nodes.at(x)
+}
+
+
+// Returns the node <x> to write it, but not its levels, see editLevels().
+func (sl *skipList) edit(x int) *skipListNode {
+	return sl.nodes.edit(x, sl.gen)
+}
+
+
+// Returns the levels of the node <x> to write them.
+func (sl *skipList) editLevels(x int) []skipListLevel {
+	n := sl.edit(x)
+	if n.gen != sl.gen {
+		n.levels = slices.Clone(n.levels)
+		n.gen = sl.gen
+	}
+	return n.levels
+}
+
+
+// Returns a random level for a new node.
+func (sl *skipList) randomLevel() int {
+	level := 1
+	for level < skipListMaxLevel && rand.Intn(skipListP) == 0 {
+		level++
+	}
+	return level
+}
+
+
+// Finds the last node before <pos> at each level.
+// rank[i] is the number of members of the nodes up to update[i].
+func (sl *skipList) path(pos int) (rank [skipListMaxLevel]int, update [skipListMaxLevel]int) {
+	x, n, sum := 0, sl.node(0), 0
+	for i := sl.level - 1; i >= 0; i-- {
+		for next := n.levels[i].forward; next != 0; next = n.levels[i].forward {
+			m := sl.node(next)
+			if m.pos >= pos {
+				break
+			}
+			sum += n.levels[i].span
+			x, n = next, m
+		}
+		update[i], rank[i] = x, sum
+	}
+	return
+}
+
+
+// Returns the node at <pos>, 0 if there is none.
+func (sl *skipList) find(pos int) int {
+	_, update := sl.path(pos)
+	if x := sl.node(update[0]).levels[0].forward; x != 0 && sl.node(x).pos == pos {
+		return x
+	}
+	return 0
+}
+
+
+func (sl *skipList) leaf(pos int) *TreeNode {
+	if x := sl.find(pos); x != 0 {
+		return sl.node(x).leaf
+	}
+	return nil
+}
+
+
+func (sl *skipList) setLeaf(pos int, node *TreeNode) {
+	x := sl.find(pos)
+	if x == 0 {
+		x = sl.insert(pos)
+	}
+	sl.edit(x).leaf = node
+}
+
+
+// Inserts an empty node at <pos>, which must not exist.
+// Returns the index of the node.
+func (sl *skipList) insert(pos int) int {
+	rank, update := sl.path(pos)
+
+	level := sl.randomLevel()
+	if level > sl.level {
+		head := sl.editLevels(0)
+		for i := sl.level; i < level; i++ {
+			update[i] = 0
+			head[i] = skipListLevel{0, sl.total}
+		}
+		sl.level = level
+	}
+
+	// reuse the index of a deleted node
+	x := sl.nodes.size
+	if n := len(sl.free); n > 0 {
+		x, sl.free = sl.free[n - 1], sl.free[:n - 1]
+	} else {
+		sl.nodes.resize(x + 1, sl.gen)
+	}
+	levels := make([]skipListLevel, level)
+	sl.nodes.set(x, skipListNode{pos: pos, levels: levels, gen: sl.gen}, sl.gen)
+
+	for i := 0; i < level; i++ {
+		// the span of update[i] covers rank[0] - rank[i] members before x, and the rest after x
+		prev := sl.editLevels(update[i])
+		levels[i].forward = prev[i].forward
+		levels[i].span = prev[i].span - (rank[0] - rank[i])
+		prev[i].forward = x
+		prev[i].span = rank[0] - rank[i]
+	}
+	return x
+}
+
+
+// Deletes <x>, which must be empty.
+func (sl *skipList) delete(x int, update [skipListMaxLevel]int) {
+	levels := sl.node(x).levels
+	for i := 0; i < sl.level; i++ {
+		if sl.node(update[i]).levels[i].forward == x {
+			prev := sl.editLevels(update[i])
+			prev[i].span += levels[i].span
+			prev[i].forward = levels[i].forward
+		}
+	}
+	sl.nodes.set(x, skipListNode{}, sl.gen)
+	sl.free = append(sl.free, x)
+
+	for sl.level > 1 && sl.node(0).levels[sl.level - 1].forward == 0 {
+		sl.level--
+	}
+}
+
+
+// The node is created if it does not exist, and deleted when it becomes empty.
+func (sl *skipList) add(pos, delta int) {
+	_, update := sl.path(pos)
+	x := sl.node(update[0]).levels[0].forward
+	if x == 0 || sl.node(x).pos != pos {
+		x = sl.insert(pos)
+		_, update = sl.path(pos)
+	}
+
+	for i := 0; i < sl.level; i++ {
+		sl.editLevels(update[i])[i].span += delta
+	}
+	n := sl.edit(x)
+	n.count += delta
+	sl.total += delta
+
+	if n.count == 0 {
+		sl.delete(x, update)
+	}
+}
+
+
+func (sl *skipList) addAll(positions, deltas []int) {
+	for k, pos := range positions {
+		sl.add(pos, deltas[k])
+	}
+}
+
+
+func (sl *skipList) prefix(pos int) int {
+	rank, _ := sl.path(pos)
+	return rank[0]
+}
+
+
+func (sl *skipList) prefixAll(positions []int) []int {
+	sums := make([]int, len(positions))
+	for k, pos := range positions {
+		sums[k] = sl.prefix(pos)
+	}
+	return sums
+}
+
+
+func (sl *skipList) search(rank int) (pos, offset int) {
+	n, sum := sl.node(0), 0
+	for i := sl.level - 1; i >= 0; i-- {
+		for n.levels[i].forward != 0 && sum + n.levels[i].span <= rank {
+			sum += n.levels[i].span
+			n = sl.node(n.levels[i].forward)
+		}
+	}
+	return sl.node(n.levels[0].forward).pos, rank - sum
+}
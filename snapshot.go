@@ -0,0 +1,112 @@
+package ranktree
+
+import (
+	"io"
+	"iter"
+)
+
+
+// RankTreeView is a read-only view of a RankTree.
+// Both *RankTree and the snapshots returned by Snapshot() implement it.
+type RankTreeView interface {
+	Card() int
+	Score(member string) int
+	MScore(members ...string) (scores []int, ok []bool)
+	Rank(member string) int
+	RevRank(member string) int
+	MRank(members ...string) (ranks []int, ok []bool)
+	MRevRank(members ...string) (ranks []int, ok []bool)
+	ScoreAtRank(rank int) int
+	ScoreAtRevRank(rank int) int
+	RankOfScore(score int) int
+	RevRankOfScore(score int) int
+	Count(min, max int) int
+	Range(start, end int) []string
+	RevRange(start, end int) []string
+	RangeWithScore(start, end int) []RankWithScore
+	RevRangeWithScore(start, end int) []RankWithScore
+	RangeByScore(min, max int) []RankWithScore
+	RevRangeByScore(min, max int) []RankWithScore
+	Around(member string, before, after int, reverse bool) (ranks []RankWithScore, first int)
+	Iterator(reverse bool) *Iterator
+	All() iter.Seq2[string, int]
+	Backward() iter.Seq2[string, int]
+	ScoreRange(min, max int) iter.Seq2[string, int]
+	WriteTo(w io.Writer) (n int64, err error)
+}
+
+
+// snapshot hides the write methods of the frozen RankTree.
+type snapshot struct {
+	RankTreeView
+}
+
+
+// Snapshot returns a read-only view of the current state of the RankTree, which is not changed
+// by later modifications of the RankTree, e.g. to page through RevRange() over several requests.
+// Creating a snapshot is O(1), the storage is shared and copied on write: a modification of the RankTree
+// copies only the parts it touches, i.e. the leaf node, the path of its counts and of its member
+// in the member index, and the snapshot keeps the old ones. Iterators of the snapshot stay valid.
+// A snapshot may be read concurrently with the modifications of the RankTree.
+func (tree *RankTree) Snapshot() RankTreeView {
+	frozen := *tree
+	frozen.backend = tree.backend.share()
+	frozen.observers = nil
+	frozen.watchers = nil
+	frozen.autoExtend = false
+
+	tree.gen = newGen()
+	return snapshot{&frozen}
+}
+
+
+// Clone returns a fully independent copy of the RankTree, e.g. for "what-if" simulations.
+// Leaf nodes, the member index, the linked list and members are copied without re-adding the members.
+// Observers, watchers and the random source are not copied.
+func (tree *RankTree) Clone() *RankTree {
+	clone := *tree
+	clone.observers = nil
+	clone.watchers = nil
+	clone.rand = nil
+	clone.copyNodes()
+	return &clone
+}
+
+
+// Replaces the leaf nodes, the member index, the linked list and the backend of the RankTree with copies
+// in a new generation. Leaf nodes and members are copied into contiguous blocks of memory.
+func (tree *RankTree) copyNodes() {
+	old := *tree
+	tree.gen = newGen()
+	tree.scores = scoreMap{}
+	tree.list = leafList{}
+	tree.backend = old.backend.empty()
+	tree.create(tree.low, tree.high)
+
+	nodes := make([]TreeNode, old.list.len)
+	members := make([]string, 0, tree.count)
+	positions := make([]int, len(nodes))
+	counts := make([]int, len(nodes))
+
+	// from the lowest score, for the sorted positions
+	i := 0
+	for leaf := old.list.back(); leaf != nil; leaf = old.list.prev(leaf) {
+		node := &nodes[i]
+
+		// limit the capacity, so that an append does not overwrite the next leaf
+		offset := len(members)
+		members = append(members, leaf.members...)
+		node.score = leaf.score
+		node.members = members[offset : len(members) : len(members)]
+		node.gen = tree.gen
+		tree.list.insertAfter(node, nil, tree.gen)
+
+		for _, member := range node.members {
+			tree.scores.set(member, node.score, tree.gen)
+		}
+		tree.backend.setLeaf(node.score - tree.low, node)
+		positions[i], counts[i] = node.score - tree.low, len(node.members)
+		i++
+	}
+	tree.backend.addAll(positions, counts)
+}
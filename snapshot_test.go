@@ -0,0 +1,234 @@
+package ranktree
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+
+func TestRankTree_Snapshot(t *testing.T) {
+	tree, err := New(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 10)
+	tree.Add("b", 20)
+	tree.Add("c", 20)
+	tree.Add("d", 30)
+
+	view := tree.Snapshot()
+	if _, ok := view.(interface{ Add(string, int) bool }); ok {
+		t.Error("snapshot has a write method")
+	}
+
+	// page 1 before the writes
+	checkRank(t, view.RevRange(0, 1), []string{"d", "b"})
+
+	tree.IncrementBy("a", 50)
+	tree.Remove("d")
+	tree.Add("e", 25)
+	tree.UpdateScore("b", 5, false)
+	if _, err := tree.Apply([]Op{{Type: OpIncrement, Member: "c", Score: 1}, {Type: OpAdd, Member: "f", Score: 0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// page 2 after the writes
+	checkRank(t, view.RevRange(2, 3), []string{"c", "a"})
+	checkRankWithScore(t, view.RangeWithScore(0, -1), []string{"a", "b", "c", "d"}, []int{10, 20, 20, 30})
+	if n := view.Card(); n != 4 {
+		t.Errorf("view.Card() = %d, want %d", n, 4)
+	}
+	if n := view.Rank("d"); n != 3 {
+		t.Errorf("view.Rank(\"d\") = %d, want %d", n, 3)
+	}
+	if n := view.Score("e"); n != -1 {
+		t.Errorf("view.Score(\"e\") = %d, want %d", n, -1)
+	}
+	if n := view.Count(15, 25); n != 2 {
+		t.Errorf("view.Count(15, 25) = %d, want %d", n, 2)
+	}
+
+	var b strings.Builder
+	view.WriteTo(&b)
+	if want := "ranktree 1 0 100 4\n10 \"a\"\n20 \"b\"\n20 \"c\"\n30 \"d\"\n"; b.String() != want {
+		t.Errorf("view.WriteTo() = %q, want %q", b.String(), want)
+	}
+
+	checkRankWithScore(t, tree.RangeWithScore(0, -1), []string{"f", "b", "c", "e", "a"}, []int{0, 5, 21, 25, 60})
+	checkRankTree(t, tree, 0, 100, 5)
+	checkTreeCounts(t, tree)
+	checkListOrder(t, tree)
+}
+
+
+func TestRankTree_SnapshotIterator(t *testing.T) {
+	tree, err := New(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		tree.Add(string(rune('a' + i)), i * 10)
+	}
+
+	view := tree.Snapshot()
+	it := view.Iterator(true)
+	var members []string
+	for it.Next() {
+		// writes do not invalidate iterators of the snapshot
+		tree.Remove(it.Member())
+		tree.Add(it.Member() + "2", 100 - it.Score())
+		members = append(members, it.Member())
+	}
+	checkRank(t, members, []string{"j", "i", "h", "g", "f", "e", "d", "c", "b", "a"})
+
+	// another snapshot after the copy, the old one is not changed
+	view2 := tree.Snapshot()
+	tree.Remove("a2")
+	tree.ExtendRange(0, 1000)
+	tree.Add("x", 1000)
+	tree.ShrinkRange(0, 200, ShrinkEvict)
+	tree.Compact()
+
+	checkRank(t, view.Range(0, 1), []string{"a", "b"})
+	checkRank(t, view2.Range(0, 1), []string{"j2", "i2"})
+	checkRank(t, view2.RevRange(0, 0), []string{"a2"})
+	checkRank(t, tree.RevRange(0, 0), []string{"b2"})
+	checkTreeCounts(t, tree)
+	checkListOrder(t, tree)
+}
+
+
+func TestRankTree_SnapshotCopyOnWrite(t *testing.T) {
+	tree, err := New(0, 1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100000; i++ {
+		tree.Add(fmt.Sprint(i), i * 10)
+	}
+
+	// a write after a snapshot copies only the paths it touches, not the whole tree
+	var before, after runtime.MemStats
+	for k := 0; k < 3; k++ {
+		view := tree.Snapshot()
+		runtime.ReadMemStats(&before)
+		tree.IncrementBy("5", 1)
+		tree.Add("x", 500000)
+		tree.Remove("70000")
+		runtime.ReadMemStats(&after)
+
+		if n := after.TotalAlloc - before.TotalAlloc; n > 256 << 10 {
+			t.Errorf("writes after Snapshot() allocated %d bytes", n)
+		}
+		if view.Score("5") != 50 + k || view.Score("70000") != 700000 {
+			t.Errorf("view.Score() = %d, %d after the writes", view.Score("5"), view.Score("70000"))
+		}
+		tree.Add("70000", 700000)
+		tree.Remove("x")
+	}
+	checkRankTree(t, tree, 0, 1 << 20, 100000)
+	checkTreeCounts(t, tree)
+}
+
+
+func TestRankTree_SnapshotConcurrent(t *testing.T) {
+	tree, err := New(0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		tree.Add(fmt.Sprint(i), i)
+	}
+
+	// snapshots are read by other goroutines while the tree is written
+	var wg sync.WaitGroup
+	for k := 0; k < 4; k++ {
+		view := tree.Snapshot()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i += 7 {
+				if n := view.Rank(fmt.Sprint(i)); n != i {
+					t.Errorf("view.Rank(%q) = %d, want %d", fmt.Sprint(i), n, i)
+				}
+			}
+			checkRank(t, view.Range(0, 2), []string{"0", "1", "2"})
+		}()
+		for i := 0; i < 1000; i += 3 {
+			tree.IncrementBy(fmt.Sprint(i), 1)
+			tree.IncrementBy(fmt.Sprint(i), -1)
+		}
+		tree.Remove("500")
+		tree.Add("500", 500)
+	}
+	wg.Wait()
+	checkTreeCounts(t, tree)
+	checkListOrder(t, tree)
+}
+
+
+func TestRankTree_Clone(t *testing.T) {
+	tree := newResizeTestTree(t, 0, 50, WithAutoExtend())
+	tree.Remove("m10", "n10", "m20")
+	cancel := tree.OnChange(func(e Event) {
+		t.Errorf("observer of the original tree is called by the clone, %v", e)
+	})
+
+	clone := tree.Clone()
+	checkRankWithScore(t, clone.RangeWithScore(0, -1), tree.Range(0, -1), scoresOf(tree.RangeWithScore(0, -1)))
+	checkTreeCounts(t, clone)
+	checkListOrder(t, clone)
+
+	// no shared mutable state
+	if clone.backend == tree.backend || clone.gen == tree.gen || clone.list.elements.root == tree.list.elements.root || clone.scores.root == tree.scores.root {
+		t.Error("clone shares the backend, the list or the member index")
+	}
+	for member := range tree.scores.all() {
+		node, _ := tree.node(member)
+		c, _ := clone.node(member)
+		if c == node || &c.members[0] == &node.members[0] || c.gen != clone.gen {
+			t.Errorf("clone shares the node of %s", member)
+		}
+	}
+
+	// modify the clone, the original is not changed
+	want := tree.RangeWithScore(0, -1)
+	clone.Add("x", 5)
+	clone.Add("y", 1000)
+	clone.IncrementBy("m30", 1)
+	clone.Remove("n40")
+	if _, err := clone.Apply([]Op{{Type: OpUpdate, Member: "m5", Score: 10}}); err != nil {
+		t.Fatal(err)
+	}
+	clone.Compact()
+
+	checkRankWithScore(t, tree.RangeWithScore(0, -1), namesOf(want), scoresOf(want))
+	checkTreeCounts(t, tree)
+	checkListOrder(t, tree)
+	checkTreeCounts(t, clone)
+	checkListOrder(t, clone)
+	if n := clone.Card(); n != len(want) + 1 {
+		t.Errorf("clone.Card() = %d, want %d", n, len(want) + 1)
+	}
+
+	// modify the original, the clone is not changed
+	cancel()
+	tree.Remove("m0")
+	if n := clone.Score("m0"); n != 0 {
+		t.Errorf("clone.Score(\"m0\") = %d, want %d", n, 0)
+	}
+}
+
+
+func namesOf(ranks []RankWithScore) []string {
+	names := make([]string, len(ranks))
+	for i, v := range ranks {
+		names[i] = v.Member
+	}
+	return names
+}
@@ -0,0 +1,74 @@
+package ranktree
+
+import (
+	"math"
+)
+
+
+// Bucket is a score range [Low, High] of a histogram, with the number of members in it.
+type Bucket struct {
+	Low		int
+	High	int
+	Count	int
+}
+
+
+// Percentile returns the percentage (0 to 100) of the other members with a score lower than <member>.
+// If member does not exist, -1 is returned.
+func (tree *RankTree) Percentile(member string) float64 {
+	score, ok := tree.scores.get(member)
+	if ok == false {
+		return -1
+	}
+
+	if tree.count == 1 {
+		return 0
+	}
+	return float64(tree.countLeftArea(score)) * 100 / float64(tree.count - 1)
+}
+
+
+// ScoreAtQuantile returns the lowest score which is greater than or equal to the scores of
+// a fraction <q> (0 to 1) of the members, e.g. ScoreAtQuantile(0.9) returns the 90th percentile cutoff.
+// If the RankTree is empty, or <q> is out of the range, -1 is returned.
+func (tree *RankTree) ScoreAtQuantile(q float64) int {
+	if tree.count == 0 || q < 0 || q > 1 {
+		return -1
+	}
+
+	// nearest rank
+	rank := int(math.Ceil(q * float64(tree.count))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return tree.ScoreAtRank(rank)
+}
+
+
+// Histogram returns the number of members in buckets of <bucketWidth> scores.
+// Buckets are aligned to the lower bound of the score range,
+// and only cover the scores between the lowest and the highest score of members.
+// If the RankTree is empty, or bucketWidth is not positive, nil is returned.
+func (tree *RankTree) Histogram(bucketWidth int) (buckets []Bucket) {
+	if tree.count == 0 || bucketWidth <= 0 {
+		return nil
+	}
+
+	lowest := tree.list.back().score
+	highest := tree.list.head().score
+
+	// the bounds are compared before they are added, so that they do not overflow near math.MaxInt
+	low := tree.minScore + (lowest - tree.minScore) / bucketWidth * bucketWidth
+	for {
+		high := tree.maxScore
+		if tree.maxScore - low > bucketWidth - 1 {
+			high = low + bucketWidth - 1
+		}
+		buckets = append(buckets, Bucket{low, high, tree.Count(low, high)})
+
+		if high >= highest {
+			return
+		}
+		low = high + 1
+	}
+}
@@ -0,0 +1,144 @@
+package ranktree
+
+import (
+	"math"
+	"testing"
+)
+
+
+func TestRankTree_Percentile(t *testing.T) {
+	tree, err := New(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 10)
+	if n := tree.Percentile("a"); n != 0 {
+		t.Errorf("tree.Percentile(\"a\") = %v, want %v", n, 0)
+	}
+
+	tree.Add("b", 20)
+	tree.Add("c", 20)
+	tree.Add("d", 30)
+	tree.Add("e", 40)
+
+	tests := []struct {
+		member	string
+		want	float64
+	}{
+		{"a", 0},
+		{"b", 25},
+		{"c", 25},
+		{"d", 75},
+		{"e", 100},
+		{"f", -1},
+	}
+	for _, tt := range tests {
+		if n := tree.Percentile(tt.member); n != tt.want {
+			t.Errorf("tree.Percentile(%q) = %v, want %v", tt.member, n, tt.want)
+		}
+	}
+}
+
+
+func TestRankTree_ScoreAtQuantile(t *testing.T) {
+	tree, err := New(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := tree.ScoreAtQuantile(0.5); n != -1 {
+		t.Errorf("tree.ScoreAtQuantile(0.5) = %d, want %d", n, -1)
+	}
+
+	for i := 1; i <= 10; i++ {
+		tree.Add(string(rune('a' + i)), i * 10)
+	}
+
+	tests := []struct {
+		q		float64
+		want	int
+	}{
+		{0, 10},
+		{0.05, 10},
+		{0.1, 10},
+		{0.11, 20},
+		{0.5, 50},
+		{0.9, 90},
+		{1, 100},
+		{-0.1, -1},
+		{1.1, -1},
+	}
+	for _, tt := range tests {
+		if n := tree.ScoreAtQuantile(tt.q); n != tt.want {
+			t.Errorf("tree.ScoreAtQuantile(%v) = %d, want %d", tt.q, n, tt.want)
+		}
+	}
+}
+
+
+func TestRankTree_Histogram(t *testing.T) {
+	tree, err := New(1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buckets := tree.Histogram(10); buckets != nil {
+		t.Errorf("tree.Histogram(10) = %v, want nil", buckets)
+	}
+
+	tree.Add("a", 15)
+	tree.Add("b", 20)
+	tree.Add("c", 21)
+	tree.Add("d", 45)
+	tree.Add("e", 100)
+
+	want := []Bucket{{11, 20, 2}, {21, 30, 1}, {31, 40, 0}, {41, 50, 1}}
+	tree.Remove("e")
+	buckets := tree.Histogram(10)
+	if len(buckets) != len(want) {
+		t.Fatalf("len(buckets) = %d, want %d", len(buckets), len(want))
+	}
+	for i, b := range buckets {
+		if b != want[i] {
+			t.Errorf("buckets[%d] = %v, want %v", i, b, want[i])
+		}
+	}
+
+	tree.Add("e", 100)
+	buckets = tree.Histogram(30)
+	want = []Bucket{{1, 30, 3}, {31, 60, 1}, {61, 90, 0}, {91, 100, 1}}
+	if len(buckets) != len(want) {
+		t.Fatalf("len(buckets) = %d, want %d", len(buckets), len(want))
+	}
+	for i, b := range buckets {
+		if b != want[i] {
+			t.Errorf("buckets[%d] = %v, want %v", i, b, want[i])
+		}
+	}
+}
+
+
+func TestRankTree_HistogramMaxInt(t *testing.T) {
+	tree, err := New(0, math.MaxInt, WithSkipList())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Add("a", 1)
+	tree.Add("b", math.MaxInt)
+
+	want := []Bucket{{0, 1 << 61 - 1, 1}, {1 << 61, 1 << 62 - 1, 0}, {1 << 62, 3 << 61 - 1, 0}, {3 << 61, math.MaxInt, 1}}
+	buckets := tree.Histogram(1 << 61)
+	if len(buckets) != len(want) {
+		t.Fatalf("len(buckets) = %d, want %d", len(buckets), len(want))
+	}
+	for i, b := range buckets {
+		if b != want[i] {
+			t.Errorf("buckets[%d] = %v, want %v", i, b, want[i])
+		}
+	}
+
+	if buckets := tree.Histogram(math.MaxInt); len(buckets) != 2 || buckets[1] != (Bucket{math.MaxInt, math.MaxInt, 1}) {
+		t.Errorf("tree.Histogram(math.MaxInt) = %v", buckets)
+	}
+}
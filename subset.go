@@ -0,0 +1,81 @@
+package ranktree
+
+import (
+	"sort"
+)
+
+
+// RankWithin returns the rank of <member> among the members of <set>, see Rank().
+// <member> is counted in even if it is not in <set>, members of <set> which do not exist are ignored.
+// It costs O(len(set)), independent of Card().
+// If member does not exist, -1 is returned.
+func (tree *RankTree) RankWithin(member string, set []string) int {
+	return tree.rankWithin(member, set, false)
+}
+
+
+// RevRankWithin returns the rank of <member> among the members of <set>, see RevRank().
+// <member> is counted in even if it is not in <set>, members of <set> which do not exist are ignored.
+// It costs O(len(set)), independent of Card().
+// If member does not exist, -1 is returned.
+func (tree *RankTree) RevRankWithin(member string, set []string) int {
+	return tree.rankWithin(member, set, true)
+}
+
+
+// Basic Function of RankWithin(), RevRankWithin().
+func (tree *RankTree) rankWithin(member string, set []string, reverse bool) (rank int) {
+	score, ok := tree.scores.get(member)
+	if ok == false {
+		return -1
+	}
+
+	seen := make(map[string]bool, len(set))
+	for _, v := range set {
+		other, ok := tree.scores.get(v)
+		if ok == false || v == member || seen[v] {
+			continue
+		}
+		seen[v] = true
+
+		// whether <v> is ordered before <member>, equal scores are lexicographical in both orders
+		if other == score {
+			if v < member {
+				rank++
+			}
+		} else if (other < score) != reverse {
+			rank++
+		}
+	}
+	return
+}
+
+
+// RangeWithin returns the specified range of the members of <set>, with their scores.
+// Members of <set> which do not exist are ignored, and indexes work as Range().
+// If reverse is false, members are ordered from the lowest to the highest score.
+// Otherwise, members are ordered from the highest to the lowest score.
+// Lexicographical is used for members with equal score.
+// It costs O(len(set) * log(len(set))), independent of Card().
+func (tree *RankTree) RangeWithin(set []string, start, end int, reverse bool) []RankWithScore {
+	ranks := make([]RankWithScore, 0, len(set))
+	seen := make(map[string]bool, len(set))
+	for _, v := range set {
+		if score, ok := tree.scores.get(v); ok && seen[v] == false {
+			seen[v] = true
+			ranks = append(ranks, RankWithScore{v, score})
+		}
+	}
+
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].Score != ranks[j].Score {
+			return (ranks[i].Score < ranks[j].Score) != reverse
+		}
+		return ranks[i].Member < ranks[j].Member
+	})
+
+	if sanitizeIndexes(&start, &end, len(ranks)) == 0 {
+		return make([]RankWithScore, 0)
+	}
+	return ranks[start : end + 1]
+}
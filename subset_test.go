@@ -0,0 +1,99 @@
+package ranktree
+
+import (
+	"testing"
+)
+
+
+func TestRankTree_RankWithin(t *testing.T) {
+	tree, err := New(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 1)
+	tree.Add("b", 2)
+	tree.Add("b2", 2)
+	tree.Add("c", 4)
+	tree.Add("d", 5)
+	tree.Add("e", 6)
+
+	set := []string{"e", "b2", "a", "x", "b2", "c"}
+
+	tests := []struct {
+		member	string
+		rank	int
+		revRank	int
+	}{
+		{"a", 0, 3},
+		{"b", 1, 2},
+		{"b2", 1, 2},
+		{"c", 2, 1},
+		{"d", 3, 1},
+		{"e", 3, 0},
+		{"x", -1, -1},
+	}
+	for _, tt := range tests {
+		if n := tree.RankWithin(tt.member, set); n != tt.rank {
+			t.Errorf("tree.RankWithin(%q) = %d, want %d", tt.member, n, tt.rank)
+		}
+		if n := tree.RevRankWithin(tt.member, set); n != tt.revRank {
+			t.Errorf("tree.RevRankWithin(%q) = %d, want %d", tt.member, n, tt.revRank)
+		}
+	}
+}
+
+
+func TestRankTree_RangeWithin(t *testing.T) {
+	tree, err := New(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 1)
+	tree.Add("b", 2)
+	tree.Add("b2", 2)
+	tree.Add("c", 4)
+	tree.Add("d", 5)
+	tree.Add("e", 6)
+
+	set := []string{"e", "b2", "b", "x", "b2", "c"}
+
+	checkRankWithScore(t, tree.RangeWithin(set, 0, -1, false), []string{"b", "b2", "c", "e"}, []int{2, 2, 4, 6})
+	checkRankWithScore(t, tree.RangeWithin(set, 0, -1, true), []string{"e", "c", "b", "b2"}, []int{6, 4, 2, 2})
+	checkRankWithScore(t, tree.RangeWithin(set, 1, 2, true), []string{"c", "b"}, []int{4, 2})
+	checkRankWithScore(t, tree.RangeWithin(set, -2, -1, false), []string{"c", "e"}, []int{4, 6})
+	checkRankWithScore(t, tree.RangeWithin(set, 4, 8, false), []string{}, []int{})
+	checkRankWithScore(t, tree.RangeWithin(nil, 0, -1, false), []string{}, []int{})
+}
+
+
+func TestRankTree_RankWithinEqualScores(t *testing.T) {
+	tree, err := New(1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("c", 3)
+	tree.Add("b", 3)
+	tree.Add("a", 3)
+	tree.Add("d", 5)
+
+	// ranks agree with the order of RangeWithin() in both directions
+	set := []string{"c", "a", "b", "d"}
+	for _, reverse := range []bool{false, true} {
+		for rank, v := range tree.RangeWithin(set, 0, -1, reverse) {
+			n := tree.RankWithin(v.Member, set)
+			if reverse {
+				n = tree.RevRankWithin(v.Member, set)
+			}
+			if n != rank {
+				t.Errorf("reverse %v: rank of %q = %d, want %d", reverse, v.Member, n, rank)
+			}
+		}
+	}
+
+	if n := tree.RevRankWithin("a", []string{"a", "b", "c"}); n != 0 {
+		t.Errorf("tree.RevRankWithin(\"a\") = %d, want 0", n)
+	}
+}
@@ -0,0 +1,224 @@
+package ranktree
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+
+// Validate checks the invariants of the structure of the RankTree, e.g. in tests or as a debug-mode check
+// after modifications:
+// the count of each node of the backend is the sum of its children (or the spans of the skip list are right),
+// the count of each leaf node is the number of its members, the members of a leaf node are sorted,
+// the list holds exactly the non-empty leaf nodes from the highest score,
+// every member of the member index has the score of a leaf node holding it, and Card() is the number of members.
+// It costs O(N) for the skip list, and O(range) for the other backends.
+// Returns nil if the RankTree is valid, otherwise an error describing the first violation found.
+func (tree *RankTree) Validate() error {
+	// number of members counted by the backend
+	var total int
+	var err error
+	switch b := tree.backend.(type) {
+	case *segmentTree:
+		if b.size - 1 != tree.high - tree.low || b.counts.size != segmentTreeLen(b.size) {
+			return fmt.Errorf("segment tree of %d positions, %d counts for the range [%d, %d]", b.size, b.counts.size, tree.low, tree.high)
+		}
+		total, err = tree.validateSegment(b, 0, 0, b.size - 1)
+	case *fenwickTree:
+		total, err = tree.validateFenwick(b)
+	case *skipList:
+		total, err = tree.validateSkipList(b)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the list
+	sum, length := 0, 0
+	var prev *TreeNode
+	for node := tree.list.head(); node != nil; node = tree.list.next(node) {
+		length++
+		if length > tree.list.len {
+			return fmt.Errorf("list longer than len = %d", tree.list.len)
+		}
+		if tree.list.elements.get(node.element).node != node {
+			return fmt.Errorf("list element %d does not point to its leaf node", length)
+		}
+		if tree.list.prev(node) != prev || (prev != nil && node.score >= prev.score) {
+			return fmt.Errorf("list element of score %d after score %d", node.score, prev.score)
+		}
+		if node.score < tree.low || node.score > tree.high || tree.backend.leaf(node.score - tree.low) != node {
+			return fmt.Errorf("leaf node (%d) in the list is not in the tree", node.score)
+		}
+		if len(node.members) == 0 {
+			return fmt.Errorf("leaf node (%d) in the list is empty", node.score)
+		}
+
+		sum += len(node.members)
+		prev = node
+	}
+	if length != tree.list.len || tree.list.back() != prev {
+		return fmt.Errorf("list of %d elements, len = %d", length, tree.list.len)
+	}
+
+	// the members
+	for member, score := range tree.scores.all() {
+		node := tree.find(score)
+		if node == nil || node.element == 0 {
+			return fmt.Errorf("member %q has the score %d of a leaf node not in the list", member, score)
+		}
+		if _, found := slices.BinarySearch(node.members, member); found == false {
+			return fmt.Errorf("member %q is not in its leaf node (%d)", member, node.score)
+		}
+	}
+	if tree.Card() != sum || tree.scores.len != sum || total != sum {
+		return fmt.Errorf("Card() = %d, %d members in the member index, %d in the tree, %d in the list", tree.Card(), tree.scores.len, total, sum)
+	}
+	return nil
+}
+
+
+// Checks a leaf node at <pos> against its <count> in the backend.
+func (tree *RankTree) validateLeaf(node *TreeNode, pos, count int) error {
+	if node == nil {
+		if count != 0 {
+			return fmt.Errorf("count of score %d = %d without a leaf node", tree.low + pos, count)
+		}
+		return nil
+	}
+
+	if node.score != tree.low + pos {
+		return fmt.Errorf("leaf node (%d) at score %d", node.score, tree.low + pos)
+	}
+	if count != len(node.members) {
+		return fmt.Errorf("leaf node (%d) count = %d, %d members", node.score, count, len(node.members))
+	}
+	if count > 0 && node.element == 0 {
+		return fmt.Errorf("leaf node (%d) of %d members is not in the list", node.score, count)
+	}
+	if count == 0 && node.element != 0 {
+		return fmt.Errorf("empty leaf node (%d) is in the list", node.score)
+	}
+	for i := 1; i < len(node.members); i++ {
+		if node.members[i - 1] >= node.members[i] {
+			return fmt.Errorf("leaf node (%d) members not sorted: %q", node.score, node.members)
+		}
+	}
+	return nil
+}
+
+
+// Checks the subtree of the segment tree node i with the positions [low, high].
+// Returns the count of node i.
+func (tree *RankTree) validateSegment(seg *segmentTree, i, low, high int) (count int, err error) {
+	count = int(seg.counts.get(i))
+	if low == high {
+		return count, tree.validateLeaf(seg.leaf(low), low, count)
+	}
+
+	mid := low + (high - low) / 2
+	left, err := tree.validateSegment(seg, 2 * i + 1, low, mid)
+	if err != nil {
+		return 0, err
+	}
+	right, err := tree.validateSegment(seg, 2 * i + 2, mid + 1, high)
+	if err != nil {
+		return 0, err
+	}
+
+	if count != left + right {
+		return 0, fmt.Errorf("node [%d, %d] count = %d, children %d + %d", tree.low + low, tree.low + high, count, left, right)
+	}
+	return count, nil
+}
+
+
+// Checks the counts of the positions in the Fenwick tree, O(range).
+// Returns the number of members.
+func (tree *RankTree) validateFenwick(fw *fenwickTree) (total int, err error) {
+	size := fw.tree.size - 1
+	if size - 1 != tree.high - tree.low || fw.leaves.size != size {
+		return 0, fmt.Errorf("fenwick tree of %d positions, %d leaves for the range [%d, %d]", size, fw.leaves.size, tree.low, tree.high)
+	}
+
+	// turn a copy of the tree into the counts of the positions, like grow()
+	counts := make([]int32, fw.tree.size)
+	for i := range counts {
+		counts[i] = fw.tree.get(i)
+	}
+	for i := size; i > 0; i-- {
+		if j := i + i & -i; j <= size {
+			counts[j] -= counts[i]
+		}
+	}
+
+	for pos := 0; pos < size; pos++ {
+		if err := tree.validateLeaf(fw.leaf(pos), pos, int(counts[pos + 1])); err != nil {
+			return 0, err
+		}
+		total += int(counts[pos + 1])
+	}
+	return total, nil
+}
+
+
+// Checks the nodes and the spans of the skip list, O(N * level).
+// Returns the number of members.
+func (tree *RankTree) validateSkipList(sl *skipList) (total int, err error) {
+	// positions are compared to high - low, the number of positions of [0, math.MaxInt] does not fit in an int
+	span := tree.high - tree.low
+	if span < math.MaxInt && sl.size - 1 != span {
+		return 0, fmt.Errorf("skip list of %d positions for the range [%d, %d]", sl.size, tree.low, tree.high)
+	}
+	if sl.level < 1 || sl.level > skipListMaxLevel {
+		return 0, fmt.Errorf("skip list level = %d", sl.level)
+	}
+
+	// rank of each node, i.e. the number of members up to it
+	ranks := map[int]int{0: 0}
+	sum, last := 0, -1
+	for i := sl.node(0).levels[0].forward; i != 0; i = sl.node(i).levels[0].forward {
+		x := sl.node(i)
+		if _, ok := ranks[i]; ok || x.pos <= last || x.pos > span {
+			return 0, fmt.Errorf("skip list node at score %d after score %d", tree.low + x.pos, tree.low + last)
+		}
+		if x.count <= 0 {
+			return 0, fmt.Errorf("skip list node at score %d count = %d", tree.low + x.pos, x.count)
+		}
+		if err := tree.validateLeaf(x.leaf, x.pos, x.count); err != nil {
+			return 0, err
+		}
+		sum += x.count
+		ranks[i] = sum
+		last = x.pos
+	}
+	if sum != sl.total {
+		return 0, fmt.Errorf("skip list total = %d, %d members in the nodes", sl.total, sum)
+	}
+
+	for i := 0; i < sl.level; i++ {
+		for k := 0; ; {
+			x := sl.node(k)
+			rank, ok := ranks[k]
+			if ok == false || len(x.levels) <= i {
+				return 0, fmt.Errorf("skip list level %d links a node out of level 0", i)
+			}
+
+			// the span of the last link covers the rest of the members
+			next := x.levels[i].forward
+			end := sl.total
+			if next != 0 {
+				end = ranks[next]
+			}
+			if x.levels[i].span != end - rank {
+				return 0, fmt.Errorf("skip list level %d span after score %d = %d, want %d", i, tree.low + x.pos, x.levels[i].span, end - rank)
+			}
+			if next == 0 {
+				break
+			}
+			k = next
+		}
+	}
+	return sl.total, nil
+}
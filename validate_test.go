@@ -0,0 +1,160 @@
+package ranktree
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+
+// Creates a tree with random members and scores, some of them removed or updated.
+func newValidateTestTree(t *testing.T) *RankTree {
+	tree, err := New(0, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		member := fmt.Sprintf("m%d", r.Intn(100))
+		switch r.Intn(4) {
+		case 0:
+			tree.Remove(member)
+		case 1:
+			tree.IncrementBy(member, r.Intn(10))
+		default:
+			tree.UpdateScore(member, r.Intn(201), true)
+		}
+	}
+	return tree
+}
+
+
+func TestRankTree_Validate(t *testing.T) {
+	tree := newValidateTestTree(t)
+	if err := tree.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+
+	// snapshots and clones
+	view := tree.Snapshot()
+	tree.PopMaxN(10)
+	tree.ExtendRange(0, 1000)
+	tree.Add("high", 1000)
+	for _, tree := range []*RankTree{tree, view.(snapshot).RankTreeView.(*RankTree), tree.Clone()} {
+		if err := tree.Validate(); err != nil {
+			t.Errorf("Validate() = %v", err)
+		}
+	}
+
+	tree.ShrinkRange(100, 500, ShrinkEvict)
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate() after ShrinkRange() = %v", err)
+	}
+
+	empty, _ := New(0, 10)
+	if err := empty.Validate(); err != nil {
+		t.Errorf("Validate() of an empty tree = %v", err)
+	}
+}
+
+
+func TestRankTree_ValidateMaxInt(t *testing.T) {
+	tree, err := New(0, math.MaxInt, WithSkipList())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.Add("a", 5)
+	tree.Add("b", 0)
+	tree.Add("c", math.MaxInt)
+	tree.Add("d", math.MaxInt)
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	tree.PopMax()
+	tree.IncrementBy("a", 1 << 62)
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate() after PopMax(), IncrementBy() = %v", err)
+	}
+
+	// the rebuilt range up to math.MaxInt
+	tree, _ = New(3, math.MaxInt, WithSkipList())
+	tree.Add("a", math.MaxInt)
+	tree.ExtendRange(0, 10)
+	tree.Add("b", 1)
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate() after ExtendRange() = %v", err)
+	}
+}
+
+
+func TestRankTree_ValidateCorrupted(t *testing.T) {
+	// a leaf node with members, and its neighbour in the list
+	leafOf := func(tree *RankTree) (node, next *TreeNode) {
+		for n := tree.list.head(); n != nil; n = tree.list.next(n) {
+			if len(n.members) > 1 && tree.list.next(n) != nil {
+				return n, tree.list.next(n)
+			}
+		}
+		t.Fatal("no leaf node with members")
+		return nil, nil
+	}
+
+	corruptions := []struct {
+		name	string
+		corrupt	func(tree *RankTree)
+	}{
+		{"backend count", func(tree *RankTree) {
+			node, _ := leafOf(tree)
+			tree.backend.add(node.score - tree.low, 1)
+		}},
+		{"leaf count", func(tree *RankTree) {
+			node, _ := leafOf(tree)
+			node.members = node.members[1:]
+		}},
+		{"unsorted members", func(tree *RankTree) {
+			node, _ := leafOf(tree)
+			node.members[0], node.members[1] = node.members[1], node.members[0]
+		}},
+		{"member index", func(tree *RankTree) {
+			node, next := leafOf(tree)
+			tree.scores.set(node.members[0], next.score, tree.gen)
+		}},
+		{"orphaned list element", func(tree *RankTree) {
+			_, next := leafOf(tree)
+			tree.list.remove(next, tree.gen)
+		}},
+		{"list order", func(tree *RankTree) {
+			node, next := leafOf(tree)
+			tree.list.remove(next, tree.gen)
+			tree.list.insertAfter(next, tree.list.prev(node), tree.gen)
+		}},
+		{"list element", func(tree *RankTree) {
+			_, next := leafOf(tree)
+			tree.list.replace(&TreeNode{score: next.score, element: next.element, members: next.members}, tree.gen)
+		}},
+		{"empty leaf node in the list", func(tree *RankTree) {
+			node, _ := leafOf(tree)
+			for _, member := range node.members {
+				tree.scores.delete(member, tree.gen)
+			}
+			tree.count -= len(node.members)
+			tree.backend.add(node.score - tree.low, -len(node.members))
+			node.members = nil
+		}},
+		{"card", func(tree *RankTree) {
+			tree.count++
+		}},
+	}
+
+	for _, c := range corruptions {
+		tree := newValidateTestTree(t)
+		c.corrupt(tree)
+		if err := tree.Validate(); err == nil {
+			t.Errorf("Validate() of a tree with a corrupted %s = nil", c.name)
+		}
+	}
+}